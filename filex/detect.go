@@ -34,12 +34,7 @@ func DetectBinaryType(ctx context.Context, vmName string, filePath string) (stri
 
 	// Remote detect
 	if vmName != "" {
-		cmd := fmt.Sprintf("goluc do detect %s", filePath)
-		fileName, err := run.RunCliSsh(vmName, cmd)
-		if err != nil {
-			return "", fmt.Errorf("failed to remote detect file typr of '%s' from '%s': %w", filePath, vmName, err)
-		}
-		return strings.TrimSpace(fileName), nil
+		return DetectBinaryTypeWith(ctx, run.GetExecutor(false, vmName), filePath)
 	}
 
 	// Local detect
@@ -77,3 +72,24 @@ func DetectBinaryType(ctx context.Context, vmName string, filePath string) (stri
 	// Default: binary
 	return "exe", nil
 }
+
+// Name: DetectBinaryTypeWith
+//
+// Description: detects the type of a file on the target of an executor.
+//
+// Parameters:
+//
+//	executor: where the file is (eg. a remote host).
+//	filePath: The path to the file on the target.
+//
+// Notes:
+//
+//   - plays `goluc do detect` on the target: goluc must be installed on the target.
+func DetectBinaryTypeWith(ctx context.Context, executor run.Executor, filePath string) (string, error) {
+	cmd := fmt.Sprintf("goluc do detect %s", filePath)
+	fileName, err := executor.Run(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to remote detect file typr of '%s' from '%s': %w", filePath, executor.Target(), err)
+	}
+	return strings.TrimSpace(fileName), nil
+}
@@ -6,6 +6,7 @@ Copyright © 2025 AB TRANSITION IT abtransitionit@hotmail.com
 package filex
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -27,6 +28,18 @@ import (
 //
 //	error: An error if the scp command fails.
 func Scp(l logx.Logger, source string, destination string) error {
+	return ScpWith(context.Background(), run.LocalExecutor{}, l, source, destination)
+}
+
+// Name: ScpWith
+//
+// Description: variant of Scp that plays the scp command with an executor.
+//
+// Parameters:
+//
+//	ctx: when the ctx is done, the scp command is killed.
+//	executor: where the scp command runs (usually run.LocalExecutor).
+func ScpWith(ctx context.Context, executor run.Executor, l logx.Logger, source string, destination string) error {
 	l.Infof("Initiating SCP transfer from %s to %s", source, destination)
 
 	// Construct the scp command string.
@@ -35,7 +48,7 @@ func Scp(l logx.Logger, source string, destination string) error {
 	command := fmt.Sprintf("scp -r %s %s", source, destination)
 
 	// Execute the command using the helper function.
-	output, err := run.RunOnLocal(command)
+	output, err := executor.Run(ctx, command)
 	if err != nil {
 		// Log the captured output for debugging purposes.
 		l.Error(strings.TrimSpace(output))
@@ -9,12 +9,14 @@ require go.uber.org/zap v1.27.0
 require (
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
 	github.com/jedib0t/go-pretty/v6 v6.6.8
+	github.com/kevinburke/ssh_config v1.6.0
 	github.com/shirou/gopsutil/v3 v3.24.5
 	github.com/spf13/cobra v1.10.1
 	github.com/spf13/pflag v1.0.10
 	github.com/spf13/viper v1.21.0
 	github.com/stretchr/testify v1.11.1
 	github.com/tidwall/pretty v1.2.1
+	golang.org/x/crypto v0.42.0
 )
 
 require (
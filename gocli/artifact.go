@@ -1,6 +1,7 @@
 package gocli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -31,6 +32,18 @@ import (
 //   - spit it in 3 functions: BuildProject, BuildProjectForCurrentPlatform, BuildProjectForPlatform
 //   - a platfrom is an os type and an os architecture
 func BuildGoProject(logger logx.Logger, projectPath, outputDir string) error {
+	return BuildGoProjectWith(context.Background(), run.LocalExecutor{}, logger, projectPath, outputDir)
+}
+
+// Name: BuildGoProjectWith
+//
+// Description: variant of BuildGoProject that plays the go build commands with an executor.
+//
+// Parameters:
+//
+//	ctx: when the ctx is done, the running go build is killed.
+//	executor: where the go build commands run (usually run.LocalExecutor).
+func BuildGoProjectWith(ctx context.Context, executor run.Executor, logger logx.Logger, projectPath, outputDir string) error {
 
 	// check parameters
 	if projectPath == "" {
@@ -65,7 +78,7 @@ func BuildGoProject(logger logx.Logger, projectPath, outputDir string) error {
 	// build the artifact for the current platform
 	logger.Infof("Building artifact '%s' from project: %s for platform:%s/%s: ", outputFile, projectPath, goos, goarch)
 	command := fmt.Sprintf("GOOS=%s GOARCH=%s go build -o %s %s", goos, goarch, outputFile, projectPath)
-	output, err := run.RunOnLocal(command)
+	output, err := executor.Run(ctx, command)
 	if err != nil {
 		logger.Errorf("go build command failed: %v with output: %s", err, output)
 		return err
@@ -77,7 +90,7 @@ func BuildGoProject(logger logx.Logger, projectPath, outputDir string) error {
 	outputFile = filepath.Join(outputDir, projectName+"-"+goos)
 	logger.Infof("Building artifact '%s' from project: %s for platform:%s/%s: ", outputFile, projectPath, goos, goarch)
 	command = fmt.Sprintf("GOOS=%s GOARCH=%s go build -o %s %s", goos, goarch, outputFile, projectPath)
-	output, err = run.RunOnLocal(command)
+	output, err = executor.Run(ctx, command)
 	if err != nil {
 		logger.Errorf("go build command failed: %v with output: %s", err, output)
 		return err
@@ -103,6 +116,13 @@ func BuildGoProject(logger logx.Logger, projectPath, outputDir string) error {
 //	bool: true if the deployment was successful.
 //	error: An error if the deployment failed.
 func DeployGoArtifact(logger logx.Logger, artifactPath, remoteDestination string) (bool, error) {
+	return DeployGoArtifactWith(context.Background(), run.LocalExecutor{}, logger, artifactPath, remoteDestination)
+}
+
+// Name: DeployGoArtifactWith
+//
+// Description: variant of DeployGoArtifact that plays the scp command with an executor.
+func DeployGoArtifactWith(ctx context.Context, executor run.Executor, logger logx.Logger, artifactPath, remoteDestination string) (bool, error) {
 	// check parameters
 	if artifactPath == "" {
 		return false, fmt.Errorf("artifact path is empty")
@@ -117,7 +137,7 @@ func DeployGoArtifact(logger logx.Logger, artifactPath, remoteDestination string
 	}
 
 	// scp the artifact to a non root location on the remote machine - if dst path is root use filex.ScpAsSudo works
-	err := filex.Scp(logger, artifactPath, remoteDestination)
+	err := filex.ScpWith(ctx, executor, logger, artifactPath, remoteDestination)
 	if err != nil {
 		logger.Errorf("%v", err)
 		return false, err
@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -48,6 +49,11 @@ func (chart HelmChart) ListKind() (string, error) {
 
 // Returns the list of helm charts in a helm repo
 func ListChart(local bool, remoteHost string, repo HelmRepo, logger logx.Logger) (string, error) {
+	return ListChartWith(context.Background(), run.GetExecutor(local, remoteHost), repo, logger)
+}
+
+// ListChartWith: variant of ListChart that plays the cli with an executor
+func ListChartWith(ctx context.Context, executor run.Executor, repo HelmRepo, logger logx.Logger) (string, error) {
 
 	// define cli
 	// cli, err := helm.HelmRepo{Name: repoName}.ListChart()
@@ -57,7 +63,7 @@ func ListChart(local bool, remoteHost string, repo HelmRepo, logger logx.Logger)
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -68,6 +74,11 @@ func ListChart(local bool, remoteHost string, repo HelmRepo, logger logx.Logger)
 
 // Returns the list of all kind the chart will create
 func (chart HelmChart) ListNbChartKind(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return chart.ListNbChartKindWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListNbChartKindWith: variant of ListNbChartKind that plays the cli with an executor
+func (chart HelmChart) ListNbChartKindWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := chart.ListNbKind()
@@ -76,7 +87,7 @@ func (chart HelmChart) ListNbChartKind(local bool, remoteHost string, logger log
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -87,6 +98,11 @@ func (chart HelmChart) ListNbChartKind(local bool, remoteHost string, logger log
 
 // Returns the list of all kind the chart will create
 func (chart HelmChart) ListChartKind(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return chart.ListChartKindWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListChartKindWith: variant of ListChartKind that plays the cli with an executor
+func (chart HelmChart) ListChartKindWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := chart.ListKind()
@@ -95,7 +111,7 @@ func (chart HelmChart) ListChartKind(local bool, remoteHost string, logger logx.
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -104,6 +120,11 @@ func (chart HelmChart) ListChartKind(local bool, remoteHost string, logger logx.
 	return output, nil
 }
 func CreateChart(local bool, remoteHost string, chart HelmChart, logger logx.Logger) (string, error) {
+	return CreateChartWith(context.Background(), run.GetExecutor(local, remoteHost), chart, logger)
+}
+
+// CreateChartWith: variant of CreateChart that plays the cli with an executor
+func CreateChartWith(ctx context.Context, executor run.Executor, chart HelmChart, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := chart.Create()
@@ -112,7 +133,7 @@ func CreateChart(local bool, remoteHost string, chart HelmChart, logger logx.Log
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -88,6 +89,11 @@ func (release HelmRelease) cliList() (string, error) {
 
 // create a helm release into a kubernetes cluster
 func (release HelmRelease) List(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return release.ListWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListWith: variant of List that plays the cli with an executor
+func (release HelmRelease) ListWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// Check parameters
 
 	// define cli
@@ -97,7 +103,7 @@ func (release HelmRelease) List(local bool, remoteHost string, logger logx.Logge
 	}
 
 	// // play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -108,6 +114,11 @@ func (release HelmRelease) List(local bool, remoteHost string, logger logx.Logge
 }
 
 func (release HelmRelease) Create(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return release.CreateWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// CreateWith: variant of Create that plays the cli with an executor
+func (release HelmRelease) CreateWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// Check parameters
 
 	// define cli
@@ -117,7 +128,7 @@ func (release HelmRelease) Create(local bool, remoteHost string, logger logx.Log
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -128,6 +139,11 @@ func (release HelmRelease) Create(local bool, remoteHost string, logger logx.Log
 	// return cli, nil
 }
 func (release HelmRelease) DryCreate(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return release.DryCreateWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// DryCreateWith: variant of DryCreate that plays the cli with an executor
+func (release HelmRelease) DryCreateWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// Check parameters
 
 	// define cli
@@ -137,7 +153,7 @@ func (release HelmRelease) DryCreate(local bool, remoteHost string, logger logx.
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -149,6 +165,11 @@ func (release HelmRelease) DryCreate(local bool, remoteHost string, logger logx.
 }
 
 func (release HelmRelease) Delete(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return release.DeleteWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// DeleteWith: variant of Delete that plays the cli with an executor
+func (release HelmRelease) DeleteWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// Check parameters
 
 	// define cli
@@ -158,7 +179,7 @@ func (release HelmRelease) Delete(local bool, remoteHost string, logger logx.Log
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -168,6 +189,11 @@ func (release HelmRelease) Delete(local bool, remoteHost string, logger logx.Log
 }
 
 func (release HelmRelease) Describe(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return release.DescribeWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// DescribeWith: variant of Describe that plays the cli with an executor
+func (release HelmRelease) DescribeWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// Check parameters
 
 	// define cli
@@ -177,7 +203,7 @@ func (release HelmRelease) Describe(local bool, remoteHost string, logger logx.L
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
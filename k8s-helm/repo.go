@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -50,6 +51,11 @@ func (repo HelmRepo) ListChart() (string, error) {
 // Returns the list of helm repositories
 
 func ListRepo(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return ListRepoWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListRepoWith: variant of ListRepo that plays the cli with an executor
+func ListRepoWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := HelmRepo{}.cliList()
@@ -58,7 +64,7 @@ func ListRepo(local bool, remoteHost string, logger logx.Logger) (string, error)
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, HandleHelmError)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, HandleHelmError)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -84,6 +90,11 @@ func ListRepoReferenced(local bool, remoteHost string, logger logx.Logger) (stri
 
 // Add a helm repository to the client configuration file
 func AddRepo(local bool, remoteHost string, repo HelmRepo, logger logx.Logger) (string, error) {
+	return AddRepoWith(context.Background(), run.GetExecutor(local, remoteHost), repo, logger)
+}
+
+// AddRepoWith: variant of AddRepo that plays the cli with an executor
+func AddRepoWith(ctx context.Context, executor run.Executor, repo HelmRepo, logger logx.Logger) (string, error) {
 
 	// Check parameters
 
@@ -94,7 +105,7 @@ func AddRepo(local bool, remoteHost string, repo HelmRepo, logger logx.Logger) (
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -105,6 +116,11 @@ func AddRepo(local bool, remoteHost string, repo HelmRepo, logger logx.Logger) (
 }
 
 func DeleteRepo(local bool, remoteHost string, repo HelmRepo, logger logx.Logger) (string, error) {
+	return DeleteRepoWith(context.Background(), run.GetExecutor(local, remoteHost), repo, logger)
+}
+
+// DeleteRepoWith: variant of DeleteRepo that plays the cli with an executor
+func DeleteRepoWith(ctx context.Context, executor run.Executor, repo HelmRepo, logger logx.Logger) (string, error) {
 
 	// Check parameters
 
@@ -115,7 +131,7 @@ func DeleteRepo(local bool, remoteHost string, repo HelmRepo, logger logx.Logger
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -0,0 +1,40 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/run"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestRepoWith
+func TestRepoWith(t *testing.T) {
+	// create inputs for the test : a repo and an executor that has no repository configured
+	logger := logx.GetLogger()
+	repo := HelmRepo{Name: "cilium", Url: "https://helm.cilium.io"}
+	executor := run.NewFakeExecutor("o1u").On("helm repo list", "", errors.New("Error: no repositories to show"))
+	ctx := context.Background()
+
+	// add a repo
+	_, err := AddRepoWith(ctx, executor, repo, logger)
+	assert.NoError(t, err)
+
+	// list the repos: no repository is a soft error
+	output, err := ListRepoWith(ctx, executor, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, "", output)
+
+	// delete the repo
+	_, err = DeleteRepoWith(ctx, executor, repo, logger)
+	assert.NoError(t, err)
+
+	// the exact commands
+	assert.Equal(t, []string{
+		"helm repo add cilium https://helm.cilium.io && helm repo update",
+		"helm repo list",
+		"helm repo remove cilium",
+	}, executor.Commands())
+}
@@ -1,6 +1,7 @@
 package kubectl
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/abtransitionit/gocore/logx"
@@ -9,6 +10,11 @@ import (
 
 // Returns the list of ConfigMap as a string
 func ListCm(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return ListCmWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListCmWith: variant of ListCm that plays the cli with an executor
+func ListCmWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// define cli
 	cli, err := Resource{Type: "cm"}.List()
 	if err != nil {
@@ -16,7 +22,7 @@ func ListCm(local bool, remoteHost string, logger logx.Logger) (string, error) {
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -25,6 +31,11 @@ func ListCm(local bool, remoteHost string, logger logx.Logger) (string, error) {
 }
 
 func DescribeCm(local bool, remoteHost string, cm Resource, logger logx.Logger) (string, error) {
+	return DescribeCmWith(context.Background(), run.GetExecutor(local, remoteHost), cm, logger)
+}
+
+// DescribeCmWith: variant of DescribeCm that plays the cli with an executor
+func DescribeCmWith(ctx context.Context, executor run.Executor, cm Resource, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := cm.Describe()
@@ -33,7 +44,7 @@ func DescribeCm(local bool, remoteHost string, cm Resource, logger logx.Logger)
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -42,6 +53,11 @@ func DescribeCm(local bool, remoteHost string, cm Resource, logger logx.Logger)
 }
 
 func YamlCm(local bool, remoteHost string, cm Resource, logger logx.Logger) (string, error) {
+	return YamlCmWith(context.Background(), run.GetExecutor(local, remoteHost), cm, logger)
+}
+
+// YamlCmWith: variant of YamlCm that plays the cli with an executor
+func YamlCmWith(ctx context.Context, executor run.Executor, cm Resource, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := cm.Yaml()
@@ -50,7 +66,7 @@ func YamlCm(local bool, remoteHost string, cm Resource, logger logx.Logger) (str
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
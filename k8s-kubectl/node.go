@@ -1,6 +1,7 @@
 package kubectl
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/abtransitionit/gocore/logx"
@@ -9,6 +10,11 @@ import (
 
 // Returns the list of node as a string
 func ListNode(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return ListNodeWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListNodeWith: variant of ListNode that plays the cli with an executor
+func ListNodeWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// define cli
 	cli, err := Resource{Type: "node"}.List()
 	if err != nil {
@@ -16,7 +22,7 @@ func ListNode(local bool, remoteHost string, logger logx.Logger) (string, error)
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -25,6 +31,11 @@ func ListNode(local bool, remoteHost string, logger logx.Logger) (string, error)
 }
 
 func DescribeNode(local bool, remoteHost string, node Resource, logger logx.Logger) (string, error) {
+	return DescribeNodeWith(context.Background(), run.GetExecutor(local, remoteHost), node, logger)
+}
+
+// DescribeNodeWith: variant of DescribeNode that plays the cli with an executor
+func DescribeNodeWith(ctx context.Context, executor run.Executor, node Resource, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := node.Describe()
@@ -33,7 +44,7 @@ func DescribeNode(local bool, remoteHost string, node Resource, logger logx.Logg
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -42,6 +53,11 @@ func DescribeNode(local bool, remoteHost string, node Resource, logger logx.Logg
 }
 
 func YamlNode(local bool, remoteHost string, node Resource, logger logx.Logger) (string, error) {
+	return YamlNodeWith(context.Background(), run.GetExecutor(local, remoteHost), node, logger)
+}
+
+// YamlNodeWith: variant of YamlNode that plays the cli with an executor
+func YamlNodeWith(ctx context.Context, executor run.Executor, node Resource, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := node.Yaml()
@@ -50,7 +66,7 @@ func YamlNode(local bool, remoteHost string, node Resource, logger logx.Logger)
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -1,6 +1,7 @@
 package kubectl
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/abtransitionit/gocore/logx"
@@ -9,6 +10,11 @@ import (
 
 // Returns the list of ServiceAccount as a string
 func ListSa(local bool, remoteHost string, logger logx.Logger) (string, error) {
+	return ListSaWith(context.Background(), run.GetExecutor(local, remoteHost), logger)
+}
+
+// ListSaWith: variant of ListSa that plays the cli with an executor
+func ListSaWith(ctx context.Context, executor run.Executor, logger logx.Logger) (string, error) {
 	// define cli
 	cli, err := Resource{Type: "sa"}.List()
 	if err != nil {
@@ -16,7 +22,7 @@ func ListSa(local bool, remoteHost string, logger logx.Logger) (string, error) {
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -25,6 +31,11 @@ func ListSa(local bool, remoteHost string, logger logx.Logger) (string, error) {
 }
 
 func DescribeSa(local bool, remoteHost string, sa Resource, logger logx.Logger) (string, error) {
+	return DescribeSaWith(context.Background(), run.GetExecutor(local, remoteHost), sa, logger)
+}
+
+// DescribeSaWith: variant of DescribeSa that plays the cli with an executor
+func DescribeSaWith(ctx context.Context, executor run.Executor, sa Resource, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := sa.Describe()
@@ -33,7 +44,7 @@ func DescribeSa(local bool, remoteHost string, sa Resource, logger logx.Logger)
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
@@ -42,6 +53,11 @@ func DescribeSa(local bool, remoteHost string, sa Resource, logger logx.Logger)
 }
 
 func YamlSa(local bool, remoteHost string, sa Resource, logger logx.Logger) (string, error) {
+	return YamlSaWith(context.Background(), run.GetExecutor(local, remoteHost), sa, logger)
+}
+
+// YamlSaWith: variant of YamlSa that plays the cli with an executor
+func YamlSaWith(ctx context.Context, executor run.Executor, sa Resource, logger logx.Logger) (string, error) {
 
 	// define cli
 	cli, err := sa.Yaml()
@@ -50,7 +66,7 @@ func YamlSa(local bool, remoteHost string, sa Resource, logger logx.Logger) (str
 	}
 
 	// play cli
-	output, err := run.ExecuteCliQuery(cli, logger, local, remoteHost, run.NoOpErrorHandler)
+	output, err := run.ExecuteCliQueryWith(ctx, executor, cli, logger, run.NoOpErrorHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to run command: %s: %w", cli, err)
 	}
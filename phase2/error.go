@@ -0,0 +1,24 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Description: denotes a workflow, a phase or a host execution stopped because the context is done
+//
+// Notes:
+// - use errors.Is(err, ErrCancelled) to distinguish a cancelled run from a failed run
+var ErrCancelled = errors.New("cancelled")
+
+// Description: denotes a host execution that did not complete within the timeout of the phase
+var ErrTimeout = errors.New("timed out")
+
+// Description: denotes a function alias (or a function name) that is already registered
+var ErrFnDuplicate = errors.New("already registered")
+
+// Description: returns an error that wraps ErrCancelled and the cause of the context cancellation
+func errCancelled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrCancelled, context.Cause(ctx))
+}
@@ -0,0 +1,133 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/abtransitionit/gocore/logx"
+)
+
+// Description: represents the outcome of a phase run by the dag scheduler
+type phaseOutcome struct {
+	name string
+	err  error
+}
+
+// Description: runs the phases as soon as their own dependencies complete (dag scheduler)
+//
+// Notes:
+// - the graph is the one returned by buildDependencyGraph restricted to the retained phases
+// - a dependency that is not retained (ie. filtered out) is considered complete
+// - a phase whose dependency failed is not started (skipped)
+// - at most opt.MaxParallelPhase phases run at the same time (0 means no limit)
+// - once the context is done, no new phase is started and the running ones are waited for
+func (wkf *Workflow) executeDag(ctx context.Context, env *runEnv, tierListFiltered [][]Phase, logger logx.Logger) error {
+
+	// 1 - get the graph
+	_, graph, err := wkf.buildDependencyGraph()
+	if err != nil {
+		return fmt.Errorf("building dependency graph: %w", err)
+	}
+
+	// 2 - get the retained phases
+	phaseMap := make(map[string]Phase)
+	for _, tier := range tierListFiltered {
+		for _, phase := range tier {
+			phaseMap[phase.Name] = phase
+		}
+	}
+
+	// 3 - count the retained dependencies of each retained phase
+	inDegree := make(map[string]int, len(phaseMap))
+	for name, phase := range phaseMap {
+		for _, dep := range phase.Dependency {
+			if _, ok := phaseMap[dep]; ok {
+				inDegree[name]++
+			}
+		}
+	}
+
+	// 4 - get the phases that can start right now
+	var readyList []string
+	for name := range phaseMap {
+		if inDegree[name] == 0 {
+			readyList = append(readyList, name)
+		}
+	}
+	sort.Strings(readyList) // deterministic
+
+	// 5 - loop until no phase runs and no phase can start
+	outcomeCh := make(chan phaseOutcome, len(phaseMap))
+	nbRunning := 0
+	doneMap := make(map[string]bool, len(phaseMap))
+	var failedList []string
+	var errList []error
+	for {
+		// 51 - start as many ready phases as allowed
+		for len(readyList) > 0 && ctx.Err() == nil && (env.opt.MaxParallelPhase == 0 || nbRunning < env.opt.MaxParallelPhase) {
+			name := readyList[0]
+			readyList = readyList[1:]
+			nbRunning++
+			logger.Infof("👉 Starting phase %s (%d running)", name, nbRunning)
+			go func(oneItem Phase) {
+				err := oneItem.run(ctx, env, logger) // delegate the execution of the phase to this method
+				outcomeCh <- phaseOutcome{name: oneItem.Name, err: err}
+			}(phaseMap[name])
+		}
+
+		// 52 - nothing runs: no more phase can start
+		if nbRunning == 0 {
+			break
+		}
+
+		// 53 - wait for a phase to complete
+		outcome := <-outcomeCh
+		nbRunning--
+		doneMap[outcome.name] = true
+		if outcome.err != nil {
+			failedList = append(failedList, outcome.name)
+			errList = append(errList, outcome.err)
+			continue // the dependents of a failed phase never start
+		}
+
+		// 54 - release the dependents of the completed phase
+		for _, dependent := range graph[outcome.name] {
+			if _, ok := phaseMap[dependent]; !ok {
+				continue
+			}
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				readyList = append(readyList, dependent)
+			}
+		}
+		sort.Strings(readyList) // deterministic
+	}
+
+	// 6 - handle cancellation (distinct from failure)
+	if ctx.Err() != nil {
+		logger.Warnf("🛑 workflow %q cancelled: %d:%d phase(s) completed", wkf.Name, len(doneMap), len(phaseMap))
+		return fmt.Errorf("workflow %q > %w", wkf.Name, errCancelled(ctx))
+	}
+
+	// 7 - handle error
+	if len(failedList) > 0 {
+		var skippedList []string
+		for name := range phaseMap {
+			if !doneMap[name] {
+				skippedList = append(skippedList, name)
+			}
+		}
+		sort.Strings(failedList)
+		sort.Strings(skippedList)
+		if len(skippedList) > 0 {
+			logger.Warnf("⏭ phase(s) not started because a dependency failed: %v", skippedList)
+		}
+		return fmt.Errorf("errors occurred in phase(s) %v: %w", failedList, errors.Join(errList...))
+	}
+
+	// success
+	logger.Infof("• %d phase(s) complete.", len(doneMap))
+	return nil
+}
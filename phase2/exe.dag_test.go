@@ -0,0 +1,82 @@
+package phase2
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestExecuteDag
+func TestExecuteDag(t *testing.T) {
+	// create inputs for the test : a config, a function that records the running phases and a workflow a -> d, b -> c (a is slow)
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	cfg := &viperx.Viperx{Viper: v}
+	var mu sync.Mutex
+	runningMap := map[string]bool{} // the phases running
+	maxRunning := 0                 // the max number of phases running at the same time
+	overlap := false                // c started while a was running
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.AddCtx("wkf", "work", func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		runningMap[phaseName] = true
+		maxRunning = max(maxRunning, len(runningMap))
+		if phaseName == "c" && runningMap["a"] {
+			overlap = true
+		}
+		mu.Unlock()
+		if phaseName == "a" {
+			time.Sleep(300 * time.Millisecond)
+		} else {
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		delete(runningMap, phaseName)
+		mu.Unlock()
+		return true, nil
+	}))
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a": {FnAlias: "work", Node: "nodes"},
+		"b": {FnAlias: "work", Node: "nodes"},
+		"c": {FnAlias: "work", Node: "nodes", Dependency: []string{"b"}},
+		"d": {FnAlias: "work", Node: "nodes", Dependency: []string{"a"}},
+	}}
+
+	// Define test cases.
+	tests := []struct {
+		name           string     // test case name
+		opt            ExecOption // the input
+		wantOverlap    bool       // expected: c starts before a completes
+		wantMaxRunning int        // expected max number of phases running at the same time
+	}{
+		{name: "Case 1: tier scheduler waits for the tier", opt: ExecOption{}, wantOverlap: false, wantMaxRunning: 2},
+		{name: "Case 2: dag scheduler starts a phase once its dependencies complete", opt: ExecOption{Scheduler: SchedulerDag}, wantOverlap: true, wantMaxRunning: 2},
+		{name: "Case 3: dag scheduler bounded by MaxParallelPhase", opt: ExecOption{Scheduler: SchedulerDag, MaxParallelPhase: 1}, wantOverlap: false, wantMaxRunning: 1},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlap, maxRunning = false, 0
+			report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", tt.opt, logger)
+			assert.NoError(t, err)
+			if assert.NotNil(t, report) {
+				assert.Equal(t, "success", report.Status)
+				assert.Len(t, report.Phases, 4)
+			}
+			assert.Equal(t, tt.wantOverlap, overlap)
+			assert.Equal(t, tt.wantMaxRunning, maxRunning)
+		})
+	}
+
+	// an invalid option is rejected before the run
+	_, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{Scheduler: "random"}, logger)
+	assert.Error(t, err)
+}
@@ -3,6 +3,8 @@ package phase2
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/abtransitionit/gocore/logx"
 )
@@ -11,22 +13,182 @@ import (
 //
 // Notes:
 // - this function is executed inside a goroutine
-func (goFunction *GoFunction) runOnHOst(ctx context.Context, phaseName, hostName string, logger logx.Logger) error {
+// - the function is retried according to the retry policy of the phase (if any)
+// - each attempt is logged and the number of attempts is recorded in the run summary
+// - check-then-apply: the function is not run on a host already in the desired state (see Phase.Check)
+func (goFunction *GoFunction) runOnHOst(ctx context.Context, phaseName, hostName string, summary *runSummary, logger logx.Logger) error {
+	startTime := time.Now()
+	summary.notify(Event{Type: EventPhaseHostStarted, Phase: phaseName, Host: hostName})
 
-	// 1 - define the return value of the function to be executed on the host
-	var ok bool
-	var err error
+	// 1 - check the host is already in the desired state
+	if goFunction.Check != nil {
+		inDesiredState, attempt, err := goFunction.runCheck(ctx, phaseName, hostName, summary.getOutputStore(), logger)
+		status := ""
+		switch {
+		case err != nil:
+			err = fmt.Errorf("check > %w", err)
+			status = getStatus(err)
+		case inDesiredState:
+			logger.Debugf("↪ (%s) > %s > already in the desired state", phaseName, hostName)
+			status = statusUnchanged
+		case goFunction.checkOnly:
+			logger.Infof("✎ (%s) > %s > would change", phaseName, hostName)
+			status = statusWouldChange
+		}
+		if status != "" {
+			if recErr := summary.recordStatus(phaseName, hostName, startTime, attempt, status, err); recErr != nil {
+				logger.Warnf("(%s) > %s > checkpointing the outcome: %v", phaseName, hostName, recErr)
+			}
+			return err
+		}
+	} else if goFunction.checkOnly {
+		summary.recordSkipped(phaseName, hostName, statusSkippedNoCheck)
+		return nil
+	}
 
 	// 2 - execute the function
-	ok, err = goFunction.Func(goFunction.PhaseName, hostName, goFunction.ParamList, logger) // execute the task:PhaseFn (signature is important here)
+	attempt, err := goFunction.runWithRetry(ctx, phaseName, hostName, summary.getOutputStore(), logger)
+
+	// 3 - record the outcome
+	if recErr := summary.record(phaseName, hostName, startTime, attempt, err); recErr != nil {
+		logger.Warnf("(%s) > %s > checkpointing the outcome: %v", phaseName, hostName, recErr)
+	}
+	return err
+}
+
+// Description: executes code for 1 host - with retries
+//
+// Return:
+// - the number of attempts
+// - the error of the last attempt
+func (goFunction *GoFunction) runWithRetry(ctx context.Context, phaseName, hostName string, outputs *OutputStore, logger logx.Logger) (int, error) {
+
+	// 1 - define the number of attempts
+	nbAttempt := goFunction.Retry.nbAttempt()
+
+	// 2 - loop over attempts
+	var err error
+	attempt := 0
+	for attempt < nbAttempt {
+		attempt++
+
+		// 21 - execute the function
+		err = goFunction.runOnce(ctx, phaseName, hostName, outputs, logger)
+		if err == nil || attempt == nbAttempt || !goFunction.Retry.shouldRetry(err) {
+			break
+		}
+
+		// 22 - wait before next attempt
+		delay := goFunction.Retry.backoff(attempt)
+		logger.Warnf("🔁 (%s) > %s > attempt %d:%d failed, retrying in %s > %v", phaseName, hostName, attempt, nbAttempt, delay, err)
+		if sleepCtx(ctx, delay) != nil {
+			err = fmt.Errorf("phase: %s > host:%s > %w", phaseName, hostName, errCancelled(ctx))
+			break
+		}
+	}
+
+	if err != nil && nbAttempt > 1 {
+		return attempt, fmt.Errorf("after %d:%d attempt(s): %w", attempt, nbAttempt, err)
+	}
+	return attempt, err
+}
+
+// Description: executes the check function for 1 host - with retries
+//
+// Return:
+// - true if the host is already in the desired state
+// - the number of attempts
+// - the error of the last attempt
+//
+// Notes:
+// - a check function that returns false is not an error: the host is not in the desired state
+func (goFunction *GoFunction) runCheck(ctx context.Context, phaseName, hostName string, outputs *OutputStore, logger logx.Logger) (bool, int, error) {
+	var inDesiredState atomic.Bool
+	checkFunction := *goFunction
+	checkFunction.Name = goFunction.Name + " (check)"
+	checkFunction.Func = func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		ok, err := goFunction.Check(ctx, phaseName, target, params, logger)
+		inDesiredState.Store(ok && err == nil)
+		return err == nil, err
+	}
+	attempt, err := checkFunction.runWithRetry(ctx, phaseName, hostName, outputs, logger)
+	return inDesiredState.Load(), attempt, err
+}
+
+// Description: the time to wait for a function to return once its context is done
+//
+// Notes:
+// - a context-aware function kills its child processes (eg. ssh) and returns within this delay - eg. with run.RunCliLocalContext or run.ExecCliSsh that kill the process group
+// - a function that ignores its context (eg. a legacy PhaseFn) is abandoned after this delay (the workflow does not stall)
+// - the engine cannot kill an abandoned function: its child processes keep running until it returns
+// - a var so that the tests can shorten it
+var killGracePeriod = 10 * time.Second
+
+// Description: represents the value returned by a PhaseFnCtx
+type fnResult struct {
+	ok  bool
+	err error
+}
+
+// Description: executes code for 1 host - one attempt
+//
+// Notes:
+// - the attempt is bounded by the timeout of the phase (if any)
+// - a timeout is reported as ErrTimeout, a cancelled workflow as ErrCancelled
+// - the ctx passed to the function allows it to publish outputs (SetOutput)
+func (goFunction *GoFunction) runOnce(ctx context.Context, phaseName, hostName string, outputs *OutputStore, logger logx.Logger) error {
+
+	// 1 - do not start the function if the context is done
+	if ctx.Err() != nil {
+		return errCancelled(ctx)
+	}
+
+	// 2 - bound the attempt with the timeout of the phase
+	attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+	if goFunction.Timeout > 0 {
+		attemptCtx, cancel = context.WithTimeoutCause(ctx, goFunction.Timeout, ErrTimeout)
+	}
+	defer cancel()
+	attemptCtx = withOutputScope(attemptCtx, outputs, phaseName, hostName)
+
+	// 3 - execute the function
+	resultCh := make(chan fnResult, 1)
+	go func() {
+		ok, err := goFunction.Func(attemptCtx, goFunction.PhaseName, hostName, goFunction.ParamList, logger) // execute the task:PhaseFnCtx (signature is important here)
+		resultCh <- fnResult{ok: ok, err: err}
+	}()
+
+	// 4 - wait for the function to return
+	var result fnResult
+	select {
+	case result = <-resultCh:
+	case <-attemptCtx.Done():
+		// the function is expected to kill its child processes and return
+		select {
+		case result = <-resultCh:
+		case <-time.After(killGracePeriod):
+			logger.Warnf("⏱ (%s) > %s > function %s did not return %s after its context was done: abandoned", phaseName, hostName, goFunction.Name, killGracePeriod)
+			result = fnResult{ok: false, err: context.Cause(attemptCtx)}
+		}
+	}
+
+	// handle cancellation: the function stopped because the workflow context is done
+	if ctx.Err() != nil && (result.err != nil || !result.ok) {
+		return fmt.Errorf("phase: %s > host:%s > %w", phaseName, hostName, errCancelled(ctx))
+	}
+
+	// handle timeout: the function stopped because the phase timeout expired
+	if attemptCtx.Err() != nil && (result.err != nil || !result.ok) {
+		return fmt.Errorf("phase: %s > host:%s > %w after %s", phaseName, hostName, ErrTimeout, goFunction.Timeout)
+	}
 
 	// handle system eroor
-	if err != nil {
-		return err
+	if result.err != nil {
+		return result.err
 	}
 
 	// handle logic eroor
-	if !ok {
+	if !result.ok {
 		return fmt.Errorf("↪ (gofunc) phase: %s > host:%s > phase:%s > go:%s > param: %s", phaseName, hostName, goFunction.PhaseName, goFunction.Name, goFunction.ParamList)
 	}
 
@@ -0,0 +1,140 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestRunOnce
+func TestRunOnce(t *testing.T) {
+	// create inputs for the test : a short grace period, a context-aware function and a legacy one that ignore the ctx
+	logger := logx.GetLogger()
+	defer func(d time.Duration) { killGracePeriod = d }(killGracePeriod)
+	killGracePeriod = 100 * time.Millisecond
+	sleepCtx := func(d time.Duration) PhaseFnCtx {
+		return func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+			select {
+			case <-time.After(d):
+				return true, nil
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		}
+	}
+	release := make(chan struct{})
+	defer close(release)
+	legacy := AdaptPhaseFn(func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		<-release
+		return true, nil
+	})
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Define test cases.
+	tests := []struct {
+		name        string          // test case name
+		fn          PhaseFnCtx      // the input
+		timeout     time.Duration   // the input : the timeout of the phase
+		ctx         context.Context // the input
+		wantErr     error           // expected error (nil if none)
+		wantMaxTime time.Duration   // expected max duration
+	}{
+		{name: "Case 1: completes within the timeout", fn: sleepCtx(10 * time.Millisecond), timeout: time.Second, ctx: context.Background(), wantMaxTime: time.Second},
+		{name: "Case 2: per-host timeout", fn: sleepCtx(10 * time.Second), timeout: 50 * time.Millisecond, ctx: context.Background(), wantErr: ErrTimeout, wantMaxTime: time.Second},
+		{name: "Case 3: cancelled workflow", fn: sleepCtx(10 * time.Second), ctx: cancelledCtx, wantErr: ErrCancelled, wantMaxTime: time.Second},
+		{name: "Case 4: legacy function abandoned after the grace period", fn: legacy, timeout: 50 * time.Millisecond, ctx: context.Background(), wantErr: ErrTimeout, wantMaxTime: time.Second},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goFunction := &GoFunction{PhaseName: "p", Name: "fn", Func: tt.fn, Timeout: tt.timeout}
+			start := time.Now()
+			err := goFunction.runOnce(tt.ctx, "p", "h1", newOutputStore(), logger)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantErr == ErrTimeout, errors.Is(err, ErrTimeout))
+				assert.Equal(t, tt.wantErr == ErrCancelled, errors.Is(err, ErrCancelled))
+			}
+			assert.Less(t, time.Since(start), tt.wantMaxTime)
+		})
+	}
+}
+
+// Name: TestCheck
+func TestCheck(t *testing.T) {
+	// create inputs for the test : h1 is already in the desired state, h2 is not, the check fails on h3
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1", "h2", "h3"})
+	cfg := &viperx.Viperx{Viper: v}
+	var mu sync.Mutex
+	var applyList []string // the hosts on which the function is run
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "apply", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		applyList = append(applyList, phaseName+"/"+target)
+		return true, nil
+	}))
+	assert.NoError(t, registry.Add("wkf", "check", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		if target == "h3" {
+			return false, errors.New("cannot check")
+		}
+		return target == "h1", nil
+	}))
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a": {FnAlias: "apply", Check: "check", Node: "nodes"},
+		"b": {FnAlias: "apply", Node: "nodes"},
+	}}
+
+	// Define test cases.
+	tests := []struct {
+		name          string            // test case name
+		checkOnly     bool              // the input
+		wantApplyList []string          // expected phase/host pairs on which the function is run
+		wantStatus    map[string]string // expected status per phase/host
+		wantRecap     []PhaseReport     // expected number of hosts per outcome
+	}{
+		{name: "Case 1: check then apply", checkOnly: false,
+			wantApplyList: []string{"a/h2", "b/h1", "b/h2", "b/h3"},
+			wantStatus:    map[string]string{"a/h1": "unchanged", "a/h2": "success", "a/h3": "failed", "b/h1": "success", "b/h2": "success", "b/h3": "success"},
+			wantRecap:     []PhaseReport{{Phase: "a", Changed: 1, Unchanged: 1, Failed: 1}, {Phase: "b", Changed: 3}}},
+		{name: "Case 2: check only", checkOnly: true,
+			wantApplyList: nil,
+			wantStatus:    map[string]string{"a/h1": "unchanged", "a/h2": "would change", "a/h3": "failed", "b/h1": "skipped (no check)", "b/h2": "skipped (no check)", "b/h3": "skipped (no check)"},
+			wantRecap:     []PhaseReport{{Phase: "a", Changed: 1, Unchanged: 1, Failed: 1}, {Phase: "b", Skipped: 3}}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyList = nil
+			report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{CheckOnly: tt.checkOnly}, logger)
+			assert.Error(t, err) // the check fails on h3
+			assert.ElementsMatch(t, tt.wantApplyList, applyList)
+			if assert.NotNil(t, report) {
+				gotStatus := map[string]string{}
+				for _, hostReport := range report.Hosts {
+					gotStatus[hostReport.Phase+"/"+hostReport.Host] = hostReport.Status
+				}
+				assert.Equal(t, tt.wantStatus, gotStatus)
+				assert.Equal(t, tt.wantRecap, report.Phases)
+			}
+		})
+	}
+
+	// check-only mode applies nothing: it cannot be combined with a checkpoint or a rollback
+	_, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{CheckOnly: true, Rollback: true}, logger)
+	assert.Error(t, err)
+}
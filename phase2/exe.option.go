@@ -0,0 +1,73 @@
+package phase2
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/abtransitionit/gocore/viperx"
+)
+
+// Description: represents the way the phases of a workflow are scheduled
+type SchedulerMode string
+
+const (
+	// the phases run tier by tier: a tier starts once the previous one completes (default)
+	SchedulerTier SchedulerMode = "tier"
+	// a phase starts as soon as all its dependencies complete
+	SchedulerDag SchedulerMode = "dag"
+)
+
+// Description: represents the options of a workflow execution
+//
+// Notes:
+// - the zero value denotes the default options (ie. tier scheduler)
+type ExecOption struct {
+	Scheduler        SchedulerMode // "tier" (default) or "dag"
+	MaxParallelPhase int           // dag scheduler: max number of concurrently running phases (0 means no limit)
+	Checkpoint       bool          // persist the status of each phase/host into a state file
+	Resume           bool          // skip the phase/host pairs already successful in the state file (implies Checkpoint)
+	StateDir         string        // folder of the state files (default: see GetDefaultStateDir)
+	ReportPath       string        // write the run report into this file: JUnit XML if the extension is ".xml", JSON otherwise
+	Filter           PhaseFilter   // the phases to run by name, tag and dependency closure (see ParsePhaseFilter) - combined with retainSkipRange
+	Rollback         bool          // when the workflow fails (not when cancelled): run the undo function of the completed phases in reverse order on the hosts where they succeeded
+	CheckOnly        bool          // run the check functions only: report the hosts that would change (no state, no rollback)
+	Observers        []Observer    // notified of the lifecycle events of the run (see Observer)
+	Plan             bool          // resolve the hosts, the params and the functions of the phases, print the plan and return without running any phase (see Workflow.Plan)
+	Strict           bool          // validate the workflow before running it (see Workflow.Validate) and fail a phase that cannot be resolved instead of skipping it
+	PersistOutputs   bool          // write the values of the phase outputs into the state file and the report file - they may be secrets (eg. join token): only their keys are written otherwise
+}
+
+// Description: checks the options are valid
+func (opt ExecOption) check() error {
+	switch opt.Scheduler {
+	case "", SchedulerTier, SchedulerDag:
+	default:
+		return fmt.Errorf("invalid scheduler mode: %q (expected %q or %q)", opt.Scheduler, SchedulerTier, SchedulerDag)
+	}
+	if opt.MaxParallelPhase < 0 {
+		return fmt.Errorf("invalid max number of concurrent phases: %d", opt.MaxParallelPhase)
+	}
+	if opt.CheckOnly && (opt.Checkpoint || opt.Resume || opt.Rollback) {
+		return fmt.Errorf("check-only mode cannot be combined with checkpoint, resume or rollback")
+	}
+	return nil
+}
+
+// Description: returns the max number of concurrently running phases as a string to be displayed
+func (opt ExecOption) maxParallelPhaseView() string {
+	if opt.MaxParallelPhase == 0 {
+		return "no limit"
+	}
+	return strconv.Itoa(opt.MaxParallelPhase)
+}
+
+// Description: represents what the phases of a workflow run share
+//
+// Notes:
+// - created once per run by ExecuteWithOption and passed to each phase
+type runEnv struct {
+	cfg        *viperx.Viperx // the workflow config
+	fnRegistry *FnRegistry    // the GO functions used by the workflow
+	opt        ExecOption     // the execution options
+	summary    *runSummary    // the outcome of each phase and host
+}
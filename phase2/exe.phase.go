@@ -3,100 +3,270 @@ package phase2
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/abtransitionit/gocore/logx"
-	"github.com/abtransitionit/gocore/viperx"
 )
 
 // Description: manage the execution of a phase
-func (phase *Phase) run(ctx context.Context, cfg *viperx.Viperx, fnRegistry *FnRegistry, logger logx.Logger) error {
+//
+// Notes:
+// - the observers are notified when the phase starts and finishes
+func (phase *Phase) run(ctx context.Context, env *runEnv, logger logx.Logger) (err error) {
+	startTime := time.Now()
+	env.summary.notify(Event{Type: EventPhaseStarted, Phase: phase.Name})
+	defer func() {
+		env.summary.notify(Event{Type: EventPhaseFinished, Phase: phase.Name, Status: getStatus(err), Duration: time.Since(startTime), Err: err})
+	}()
 
-	// 1 - get host
-	hostList, err := getHostList(phase.Node, cfg)
-	if err != nil {
-		logger.Warnf("skipping phase %s, err: %v, ", phase.Name, err)
+	// 0 - the phase is not run (eg. its when condition is false)
+	if phase.skipReason != "" {
+		hostList, err := getHostList(phase.Node, env.cfg)
+		if err != nil {
+			hostList = []string{"none"}
+		}
+		for _, host := range hostList {
+			env.summary.recordSkipped(phase.Name, host, phase.skipReason)
+		}
+		logger.Infof("⏭ %s > %s", phase.Name, phase.skipReason)
 		return nil
 	}
 
-	// 2 - get parameter
-	paramList, err := getParamList(phase.Param, cfg, logger)
-	if err != nil {
-		logger.Warnf("skipping phase %s, err: %v", phase.Name, err)
-		return nil
+	// 1 - resolve the hosts, the params and the functions
+	resolution, err := phase.resolve(env, logger)
+	if errors.Is(err, ErrOutputNotFound) && !env.opt.CheckOnly {
+		// an upstream phase did not publish an output this phase needs: the phase fails (check-only mode: upstream phases are not applied, the phase is skipped)
+		logger.Errorf("❌ %s > %v", phase.Name, err)
+		for _, host := range resolution.hostList {
+			if recErr := env.summary.record(phase.Name, host, time.Now(), 0, err); recErr != nil {
+				logger.Warnf("(%s) > %s > checkpointing the outcome: %v", phase.Name, host, recErr)
+			}
+		}
+		return fmt.Errorf("phase %s > %w", phase.Name, err)
 	}
-	// 3 - get PhaseFn
-	phaseFn, err := getPhaseFn(phase.WkfName, phase.FnAlias, fnRegistry)
 	if err != nil {
-		logger.Warnf("skipping phase %s, err: %v", phase.Name, err)
-		// return fmt.Errorf("skipping phase %s, err: %v", phase.Name, err)
-		return nil
+		return phase.skip(env, err, logger)
 	}
+	hostList, paramList, fnEntry := resolution.hostList, resolution.paramList, resolution.fn
+
 	// 4 - get PhaseFn package and name
-	goFnPkg, goFnName := describeFn(phaseFn, logger)
+	goFnPkg, goFnName := describeFn(fnEntry.origin, logger)
 
 	// log
 	logger.Debugf("↪ %s > host:  %s > %v", phase.Name, phase.Node, hostList)
 	logger.Debugf("↪ %s > fnAlias: %s > %s/%s", phase.Name, phase.FnAlias, goFnPkg, goFnName)
 	if len(phase.Param) > 0 {
 		for i, key := range phase.Param {
-			if i < len(paramList) {
+			switch {
+			case i >= len(paramList):
+			case strings.HasPrefix(key, outputParamPrefix):
+				logger.Debugf("↪ %s > param: %s > %s", phase.Name, key, redactedOutput) // an output may be a secret
+			default:
 				logger.Debugf("↪ %s > param: %s > %v", phase.Name, key, paramList[i])
 			}
 		}
 	}
+	if phase.Timeout > 0 {
+		logger.Debugf("↪ %s > timeout: %s per host", phase.Name, phase.Timeout)
+	}
+	if phase.Check != "" {
+		logger.Debugf("↪ %s > check: %s", phase.Name, phase.Check)
+	}
+	if phase.Retry != nil {
+		logger.Debugf("↪ %s > retry: %d attempt(s) > delay: %s > maxDelay: %s > factor: %v > transientOnly: %v", phase.Name, phase.Retry.nbAttempt(), phase.Retry.Delay, phase.Retry.MaxDelay, phase.Retry.Factor, phase.Retry.TransientOnly)
+	}
 
 	// 5 - create a GoFunc instance
 	goFunction := &GoFunction{
 		PhaseName: phase.Name,
 		Name:      goFnName,
 		ParamList: paramList,
-		Func:      phaseFn,
+		Func:      fnEntry.fn,
+		Retry:     phase.Retry,
+		Timeout:   phase.Timeout,
+		Check:     resolution.check.fn,
+		checkOnly: env.opt.CheckOnly,
+	}
+	// 6 - split the hosts into batches (rolling)
+	batchList := phase.getBatchList(hostList)
+	if len(batchList) > 1 || phase.MaxParallel > 0 {
+		logger.Debugf("↪ %s > %d host(s) > %d batch(es) > max parallel host(s): %d", phase.Name, len(hostList), len(batchList), phase.MaxParallel)
+	}
+
+	// 7 - run the batches one after another
+	var errList []error
+	var hostTimeoutList []string
+	var abortErr error
+	for batchIdx, batch := range batchList {
+		batchErrList, batchTimeoutList := phase.runBatch(ctx, env, goFunction, batch, logger)
+		errList = append(errList, batchErrList...)
+		hostTimeoutList = append(hostTimeoutList, batchTimeoutList...)
+
+		// 71 - abort the remaining batches when the failure ratio of the batch exceeds the threshold
+		isLast := batchIdx == len(batchList)-1
+		if isLast || phase.MaxFailRatio == nil || ctx.Err() != nil {
+			continue
+		}
+		failRatio := float64(len(batchErrList)) / float64(len(batch))
+		if failRatio > *phase.MaxFailRatio {
+			var abortedList []string
+			for _, nextBatch := range batchList[batchIdx+1:] {
+				for _, host := range nextBatch {
+					env.summary.recordSkipped(phase.Name, host, "skipped (aborted)")
+					abortedList = append(abortedList, host)
+				}
+			}
+			logger.Errorf("❌ %s > batch %d:%d failure ratio %.2f exceeds %.2f > remaining host(s) aborted: %v", phase.Name, batchIdx+1, len(batchList), failRatio, *phase.MaxFailRatio, abortedList)
+			abortErr = fmt.Errorf("phase %s > batch %d:%d failure ratio %.2f exceeds %.2f > %d host(s) aborted", phase.Name, batchIdx+1, len(batchList), failRatio, *phase.MaxFailRatio, len(abortedList))
+			break
+		}
+	}
+
+	// 8 - handle errors
+	nbGroutineFailed := len(errList)
+	errCombined := errors.Join(append(errList, abortErr)...)
+	if errors.Is(errCombined, ErrCancelled) {
+		logger.Warnf("🛑 %s > cancelled", phase.Name)
+		return errCombined
+	}
+	if nbGroutineFailed > 0 {
+		if len(hostTimeoutList) > 0 {
+			sort.Strings(hostTimeoutList)
+			logger.Errorf("⏱ %s > host(s) that timed out after %s: %v", phase.Name, phase.Timeout, hostTimeoutList)
+		}
+		logger.Errorf("❌ %s > nb host that failed: %d", phase.Name, nbGroutineFailed)
+		return errCombined
+	}
+
+	// 9 - handle success
+	logger.Infof("✅ %s > completes", phase.Name)
+	return nil
+}
+
+// Description: splits the hosts of a phase into batches of phase.Serial hosts
+//
+// Notes:
+// - a single batch that contains all the hosts if phase.Serial is not set
+func (phase *Phase) getBatchList(hostList []string) [][]string {
+	if phase.Serial <= 0 || phase.Serial >= len(hostList) {
+		return [][]string{hostList}
+	}
+	var batchList [][]string
+	for start := 0; start < len(hostList); start += phase.Serial {
+		end := min(start+phase.Serial, len(hostList))
+		batchList = append(batchList, hostList[start:end])
 	}
-	// 6 - manage goroutines concurrency
-	nbItem := len(hostList)
+	return batchList
+}
+
+// Description: runs a phase on a batch of hosts
+//
+// Return:
+// - the errors of the hosts that failed
+// - the hosts that timed out
+//
+// Notes:
+// - a goroutine per host
+// - at most phase.MaxParallel hosts run at the same time (0 means no limit)
+func (phase *Phase) runBatch(ctx context.Context, env *runEnv, goFunction *GoFunction, batch []string, logger logx.Logger) ([]error, []string) {
+
+	// 1 - manage goroutines concurrency
+	nbItem := len(batch)
 	var wgPhase sync.WaitGroup             // define a WaitGroup instance for each item in the list : wait for all (concurent) goroutines to complete
 	errChPhase := make(chan error, nbItem) // define a channel to collect errors from each goroutine
-	// log
-	// 61 - loop over each host of the phase AND create as many goroutines as hosts
-	// 61 - some goroutines will do SSH to play CLI remotely, other don't SSH and just play CLI locally
-	for _, host := range hostList {
+	var muTimeout sync.Mutex               // protect the list of hosts that timed out
+	var hostTimeoutList []string           // collect the hosts that timed out
+	var semaphore chan struct{}            // limit the number of hosts running at the same time
+	if phase.MaxParallel > 0 {
+		semaphore = make(chan struct{}, phase.MaxParallel)
+	}
+
+	// 2 - loop over each host of the batch AND create as many goroutines as hosts
+	// 2 - some goroutines will do SSH to play CLI remotely, other don't SSH and just play CLI locally
+	for _, host := range batch {
+		// stop scheduling new hosts once the context is done
+		if ctx.Err() != nil {
+			errChPhase <- errCancelled(ctx)
+			break
+		}
+		// resume: skip the hosts on which the phase already succeeded
+		if env.summary.isResumed(phase.Name, host) {
+			logger.Infof("⏭ (%s) > %s > already successful in a previous run", phase.Name, host)
+			env.summary.recordSkipped(phase.Name, host, "success (resumed)")
+			continue
+		}
+		// wait for a free slot - the host is not run if the context is done meanwhile
+		if semaphore != nil {
+			cancelled := false
+			select {
+			case semaphore <- struct{}{}:
+				if ctx.Err() != nil {
+					<-semaphore // release the slot
+					cancelled = true
+				}
+			case <-ctx.Done():
+				cancelled = true
+			}
+			if cancelled {
+				errChPhase <- errCancelled(ctx)
+				break
+			}
+		}
 		wgPhase.Add(1)            // Increment the WaitGroup:counter for each item
 		go func(oneItem string) { // create as many goroutine (that will run concurrently) as item AND pass the item as an argument
 			defer func() {
+				if semaphore != nil {
+					<-semaphore // release the slot
+				}
 				logger.Debugf("↩ (%s) > %s > complete", phase.Name, oneItem)
 				wgPhase.Done() // Decrement the WaitGroup counter - when the goroutine complete
 			}()
 			logger.Debugf("↪ (%s) > %s > ongoing", phase.Name, oneItem)
-			grErr := goFunction.runOnHOst(ctx, phase.Name, oneItem, logger) // delegate the execution of the function to this method
-			if grErr != nil {                                               // send goroutines error if any into the chanel
+			grErr := goFunction.runOnHOst(ctx, phase.Name, oneItem, env.summary, logger) // delegate the execution of the function to this method
+			if grErr != nil {                                                            // send goroutines error if any into the chanel
 				// log
-				logger.Errorf("❌ (%s) >  %v", phase.Name, grErr)
+				if errors.Is(grErr, ErrCancelled) {
+					logger.Warnf("🛑 (%s) > %s > %v", phase.Name, oneItem, grErr)
+				} else if errors.Is(grErr, ErrTimeout) {
+					logger.Errorf("⏱ (%s) > %s > %v", phase.Name, oneItem, grErr)
+					muTimeout.Lock()
+					hostTimeoutList = append(hostTimeoutList, oneItem)
+					muTimeout.Unlock()
+				} else {
+					logger.Errorf("❌ (%s) >  %v", phase.Name, grErr)
+				}
 				// send goroutines error if any into the chanel
 				errChPhase <- grErr
 			}
 		}(host) // pass the host to the goroutine
 	} // host loop
 
-	// 62 - Synchronisation point: Wait for all goroutines (one per host) to finish/complete - done with the help of the WaitGroup:counter
+	// 3 - Synchronisation point: Wait for all goroutines (one per host) to finish/complete - done with the help of the WaitGroup:counter
 	wgPhase.Wait()
 	close(errChPhase)
 
-	// 63 - collect errors
+	// 4 - collect errors
 	var errList []error
 	for e := range errChPhase {
 		errList = append(errList, e)
 	}
+	return errList, hostTimeoutList
+}
 
-	// 64 - handle errors
-	nbGroutineFailed := len(errList)
-	errCombined := errors.Join(errList...)
-	if nbGroutineFailed > 0 {
-		logger.Errorf("❌ %s > nb host that failed: %d", phase.Name, nbGroutineFailed)
-		return errCombined
+// Description: manage a phase that cannot be resolved (host, param, fn)
+//
+// Notes:
+// - strict mode: the phase fails
+// - otherwise: the phase is skipped (and does not fail the workflow)
+func (phase *Phase) skip(env *runEnv, err error, logger logx.Logger) error {
+	if env.opt.Strict {
+		logger.Errorf("❌ %s > cannot be resolved: %v", phase.Name, err)
+		return fmt.Errorf("phase %s > cannot be resolved: %w", phase.Name, err)
 	}
-
-	// 7 - handle success
-	logger.Infof("✅ %s > completes", phase.Name)
+	logger.Warnf("skipping phase %s, err: %v", phase.Name, err)
 	return nil
 }
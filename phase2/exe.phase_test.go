@@ -0,0 +1,129 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestGetBatchList
+func TestGetBatchList(t *testing.T) {
+	hostList := []string{"h1", "h2", "h3", "h4", "h5"}
+
+	// Define test cases.
+	tests := []struct {
+		name   string     // test case name
+		serial int        // the input
+		want   [][]string // expected batches
+	}{
+		{name: "Case 1: no serial", serial: 0, want: [][]string{hostList}},
+		{name: "Case 2: serial 1", serial: 1, want: [][]string{{"h1"}, {"h2"}, {"h3"}, {"h4"}, {"h5"}}},
+		{name: "Case 3: last batch is smaller", serial: 2, want: [][]string{{"h1", "h2"}, {"h3", "h4"}, {"h5"}}},
+		{name: "Case 4: serial above the number of hosts", serial: 10, want: [][]string{hostList}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase := &Phase{Serial: tt.serial}
+			assert.Equal(t, tt.want, phase.getBatchList(hostList))
+		})
+	}
+}
+
+// Name: TestRunBatch
+func TestRunBatch(t *testing.T) {
+	// create inputs for the test : 6 hosts and a function that fails on h1 and records the number of hosts running at the same time
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1", "h2", "h3", "h4", "h5", "h6"})
+	cfg := &viperx.Viperx{Viper: v}
+	var mu sync.Mutex
+	nbRunning, maxRunning := 0, 0
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		nbRunning++
+		maxRunning = max(maxRunning, nbRunning)
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		nbRunning--
+		mu.Unlock()
+		if target == "h1" {
+			return false, errors.New("boom")
+		}
+		return true, nil
+	}))
+	ratio := func(r float64) *float64 { return &r }
+
+	// Define test cases.
+	tests := []struct {
+		name           string            // test case name
+		phase          Phase             // the input
+		wantMaxRunning int               // expected max number of hosts running at the same time
+		wantStatus     map[string]string // expected status per host
+	}{
+		{name: "Case 1: no limit", phase: Phase{FnAlias: "work", Node: "nodes"}, wantMaxRunning: 6,
+			wantStatus: map[string]string{"h1": "failed", "h2": "success", "h3": "success", "h4": "success", "h5": "success", "h6": "success"}},
+		{name: "Case 2: max parallel hosts", phase: Phase{FnAlias: "work", Node: "nodes", MaxParallel: 2}, wantMaxRunning: 2,
+			wantStatus: map[string]string{"h1": "failed", "h2": "success", "h3": "success", "h4": "success", "h5": "success", "h6": "success"}},
+		{name: "Case 3: batches", phase: Phase{FnAlias: "work", Node: "nodes", Serial: 3}, wantMaxRunning: 3,
+			wantStatus: map[string]string{"h1": "failed", "h2": "success", "h3": "success", "h4": "success", "h5": "success", "h6": "success"}},
+		{name: "Case 4: failure ratio within the threshold", phase: Phase{FnAlias: "work", Node: "nodes", Serial: 2, MaxFailRatio: ratio(0.5)}, wantMaxRunning: 2,
+			wantStatus: map[string]string{"h1": "failed", "h2": "success", "h3": "success", "h4": "success", "h5": "success", "h6": "success"}},
+		{name: "Case 5: failure ratio exceeds the threshold", phase: Phase{FnAlias: "work", Node: "nodes", Serial: 2, MaxFailRatio: ratio(0.4)}, wantMaxRunning: 2,
+			wantStatus: map[string]string{"h1": "failed", "h2": "success", "h3": "skipped (aborted)", "h4": "skipped (aborted)", "h5": "skipped (aborted)", "h6": "skipped (aborted)"}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxRunning = 0
+			wf := &Workflow{Name: "wkf", Phases: map[string]Phase{"a": tt.phase}}
+			report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{}, logger)
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantMaxRunning, maxRunning)
+			if assert.NotNil(t, report) {
+				gotStatus := map[string]string{}
+				for _, hostReport := range report.Hosts {
+					gotStatus[hostReport.Host] = hostReport.Status
+				}
+				assert.Equal(t, tt.wantStatus, gotStatus)
+			}
+		})
+	}
+}
+
+// Name: TestRunBatchCancelled
+func TestRunBatchCancelled(t *testing.T) {
+	// create inputs for the test : 2 hosts run one at a time and a function that cancels the workflow on h1
+	logger := logx.GetLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var runList []string
+	goFunction := &GoFunction{PhaseName: "a", Name: "fn", Func: func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		runList = append(runList, target)
+		mu.Unlock()
+		cancel()
+		return true, nil
+	}}
+	env := &runEnv{cfg: &viperx.Viperx{Viper: viper.New()}, fnRegistry: NewFnRegistry(), summary: newRunSummary(nil)}
+	phase := &Phase{Name: "a", MaxParallel: 1}
+
+	// the host waiting for a slot is not run and reported as cancelled
+	errList, _ := phase.runBatch(ctx, env, goFunction, []string{"h1", "h2"}, logger)
+	assert.Equal(t, []string{"h1"}, runList)
+	if assert.Len(t, errList, 1) {
+		assert.ErrorIs(t, errList[0], ErrCancelled)
+	}
+}
@@ -0,0 +1,121 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/abtransitionit/gocore/logx"
+)
+
+// Description: runs the undo function of the completed phases after a workflow failure
+//
+// Parameters:
+// - tierList: the phases of the run (sorted by tier)
+//
+// Return:
+// - an error that lists the hosts on which an undo function failed
+//
+// Notes:
+// - the phases are rolled back one after another in reverse topological order (last tier first)
+// - a phase is rolled back on the hosts where it succeeded during the run (a resumed host is not rolled back)
+// - the hosts of a phase are rolled back concurrently
+// - a phase with no undo function is left as is
+// - the outcome is recorded in the run report (see HostReport.Rollback)
+func (wkf *Workflow) rollback(ctx context.Context, env *runEnv, tierList [][]Phase, logger logx.Logger) error {
+	logger.Infof("↩ rolling back workflow %q", wkf.Name)
+
+	var errList []error
+	for tierIdx := len(tierList) - 1; tierIdx >= 0; tierIdx-- {
+		tier := tierList[tierIdx]
+		for phaseIdx := len(tier) - 1; phaseIdx >= 0; phaseIdx-- {
+			phase := tier[phaseIdx]
+
+			// 1 - get the hosts to roll back
+			hostList := env.summary.getSuccessHostList(phase.Name)
+			if len(hostList) == 0 {
+				continue
+			}
+			if phase.Undo == "" {
+				logger.Warnf("↩ %s > no undo function > left as is on %v", phase.Name, hostList)
+				continue
+			}
+
+			// 2 - stop once the context is done: the remaining phases are left as is
+			if ctx.Err() != nil {
+				errList = append(errList, fmt.Errorf("phase %s > %w", phase.Name, errCancelled(ctx)))
+				return errors.Join(errList...)
+			}
+
+			// 3 - roll back the phase
+			if err := phase.runUndo(ctx, env, hostList, logger); err != nil {
+				errList = append(errList, err)
+			}
+		}
+	}
+	if len(errList) > 0 {
+		return errors.Join(errList...)
+	}
+	logger.Infof("↩ workflow %q > rolled back", wkf.Name)
+	return nil
+}
+
+// Description: runs the undo function of a phase on a set of hosts
+//
+// Notes:
+// - the undo function receives the params of the phase
+// - the undo function is retried and bounded like the phase (retry policy, timeout)
+func (phase *Phase) runUndo(ctx context.Context, env *runEnv, hostList []string, logger logx.Logger) error {
+
+	// 1 - resolve the function and the params
+	fnEntry, err := getPhaseFn(phase.WkfName, phase.Undo, env.fnRegistry)
+	if err != nil {
+		return fmt.Errorf("phase %s > undo > %w", phase.Name, err)
+	}
+	paramList, err := getParamList(phase.Param, env.cfg, env.summary.getOutputStore(), phase.item, logger)
+	if err != nil {
+		return fmt.Errorf("phase %s > undo > %w", phase.Name, err)
+	}
+	_, goFnName := describeFn(fnEntry.origin, logger)
+	goFunction := &GoFunction{
+		PhaseName: phase.Name,
+		Name:      goFnName,
+		ParamList: paramList,
+		Func:      fnEntry.fn,
+		Retry:     phase.Retry,
+		Timeout:   phase.Timeout,
+	}
+	logger.Infof("↩ %s > undo: %s > %v", phase.Name, phase.Undo, hostList)
+
+	// 2 - run the function on each host
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(hostList))
+	for _, host := range hostList {
+		wg.Add(1)
+		go func(oneItem string) {
+			defer wg.Done()
+			_, err := goFunction.runWithRetry(ctx, phase.Name, oneItem, env.summary.getOutputStore(), logger)
+			if recErr := env.summary.recordRollback(phase.Name, oneItem, err); recErr != nil {
+				logger.Warnf("(%s) > %s > checkpointing the rollback: %v", phase.Name, oneItem, recErr)
+			}
+			if err != nil {
+				logger.Errorf("❌ (%s) > %s > undo > %v", phase.Name, oneItem, err)
+				errCh <- fmt.Errorf("phase %s > host %s > undo > %w", phase.Name, oneItem, err)
+			}
+		}(host)
+	}
+	wg.Wait()
+	close(errCh)
+
+	// 3 - collect errors
+	var errList []error
+	for e := range errCh {
+		errList = append(errList, e)
+	}
+	if len(errList) > 0 {
+		return errors.Join(errList...)
+	}
+	logger.Infof("↩ %s > rolled back", phase.Name)
+	return nil
+}
@@ -0,0 +1,113 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestRollback
+func TestRollback(t *testing.T) {
+	// create inputs for the test : a workflow a -> b -> c -> d where c fails on h2 (b has no undo function)
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1", "h2"})
+	cfg := &viperx.Viperx{Viper: v}
+	var mu sync.Mutex
+	var undoPhaseList []string           // the phases rolled back - in order
+	undoHostMap := map[string][]string{} // phase > hosts rolled back
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		if phaseName == "c" && target == "h2" {
+			return false, errors.New("boom")
+		}
+		return true, nil
+	}))
+	assert.NoError(t, registry.Add("wkf", "undo", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(undoPhaseList) == 0 || undoPhaseList[len(undoPhaseList)-1] != phaseName {
+			undoPhaseList = append(undoPhaseList, phaseName)
+		}
+		undoHostMap[phaseName] = append(undoHostMap[phaseName], target)
+		return true, nil
+	}))
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a": {FnAlias: "work", Undo: "undo", Node: "nodes"},
+		"b": {FnAlias: "work", Node: "nodes", Dependency: []string{"a"}},
+		"c": {FnAlias: "work", Undo: "undo", Node: "nodes", Dependency: []string{"b"}},
+		"d": {FnAlias: "work", Undo: "undo", Node: "nodes", Dependency: []string{"c"}},
+	}}
+
+	// Define test cases.
+	tests := []struct {
+		name          string            // test case name
+		rollback      bool              // the input
+		wantPhaseList []string          // expected phases rolled back - in order
+		wantRollback  map[string]string // expected rollback status per phase/host
+	}{
+		{name: "Case 1: no rollback", rollback: false, wantRollback: map[string]string{}},
+		{name: "Case 2: reverse order on the hosts where the phase succeeded", rollback: true, wantPhaseList: []string{"c", "a"},
+			wantRollback: map[string]string{"a/h1": "success", "a/h2": "success", "c/h1": "success"}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			undoPhaseList, undoHostMap = nil, map[string][]string{}
+			report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{Rollback: tt.rollback}, logger)
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantPhaseList, undoPhaseList)
+			if tt.rollback {
+				assert.ElementsMatch(t, []string{"h1", "h2"}, undoHostMap["a"])
+				assert.Equal(t, []string{"h1"}, undoHostMap["c"])
+			}
+			if assert.NotNil(t, report) {
+				gotRollback := map[string]string{}
+				for _, hostReport := range report.Hosts {
+					if hostReport.Rollback != "" {
+						gotRollback[hostReport.Phase+"/"+hostReport.Host] = hostReport.Rollback
+					}
+				}
+				assert.Equal(t, tt.wantRollback, gotRollback)
+			}
+		})
+	}
+}
+
+// Name: TestRollbackCancelled
+func TestRollbackCancelled(t *testing.T) {
+	// create inputs for the test : 3 tiers that succeeded and a cancelled context
+	logger := logx.GetLogger()
+	registry := NewFnRegistry()
+	nbUndo := 0
+	assert.NoError(t, registry.Add("wkf", "undo", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		nbUndo++
+		return true, nil
+	}))
+	summary := newRunSummary(nil)
+	tierList := [][]Phase{}
+	for _, name := range []string{"a", "b", "c"} {
+		assert.NoError(t, summary.record(name, "h1", time.Now(), 1, nil))
+		tierList = append(tierList, []Phase{{WkfName: "wkf", Name: name, Undo: "undo"}})
+	}
+	env := &runEnv{cfg: &viperx.Viperx{Viper: viper.New()}, fnRegistry: registry, summary: summary}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// nothing is rolled back and the cancellation is reported once
+	err := (&Workflow{Name: "wkf"}).rollback(ctx, env, tierList, logger)
+	assert.ErrorIs(t, err, ErrCancelled)
+	var joinErr interface{ Unwrap() []error }
+	if assert.ErrorAs(t, err, &joinErr) {
+		assert.Len(t, joinErr.Unwrap(), 1)
+	}
+	assert.Equal(t, 0, nbUndo)
+}
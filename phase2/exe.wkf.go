@@ -2,8 +2,10 @@ package phase2
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/abtransitionit/gocore/list"
 	"github.com/abtransitionit/gocore/logx"
@@ -21,6 +23,7 @@ import (
 //
 // Return:
 //   - an error if something went wrong
+//   - an error that wraps ErrCancelled if the ctx is done before the workflow completes
 //
 // Example Usage:
 //
@@ -28,6 +31,7 @@ import (
 //
 // Notes:
 //
+// - runs the workflow with the default options (ie. tier scheduler) - see ExecuteWithOption
 // - the order of the phases is based on their dependency ==> topological sort => concept of tier
 // - a tier is a ordered set of phases
 // - a phase denotes a GO function that takes parameters
@@ -38,60 +42,234 @@ import (
 //
 // Todo:
 //  - check syntax of the config yaml
-//  - check syntax of the workflow yaml
 //  - check fn    is resolved in the config
+//
+// Done (see Workflow.Validate and ExecOption.Strict):
+//  - check syntax of the workflow yaml
 //  - check param is resolved in the config
 //  - check node  is resolved in the config
 //  - check fn  is registred
 
 func (wkf *Workflow) Execute(ctx context.Context, cfg *viperx.Viperx, fnRegistry *FnRegistry, retainSkipRange string, logger logx.Logger) error {
+	_, err := wkf.ExecuteWithOption(ctx, cfg, fnRegistry, retainSkipRange, ExecOption{}, logger)
+	return err
+}
+
+// Description: execute/run a workflow with options
+//
+// Parameters:
+//   - ctx
+//   - cfg : the workflow config file as a struct that contains all the vars used by the workflow
+//   - fnRegistry : contains a map of all GO functions used by the workflow
+//   - retainSkipRange : manage the phases to retain/skip in the workflow
+//   - opt : the execution options (eg. scheduler mode)
+//   - logger
+//
+// Return:
+//   - the run report (each phase, host, duration, attempts, status) - nil if the workflow cannot start
+//   - an error if something went wrong
+//
+// Example Usage:
+//
+//	report, err := workflow.ExecuteWithOption(ctx, cfg, &fnRegistry, retainSkipRange, phase2.ExecOption{Scheduler: phase2.SchedulerDag, MaxParallelPhase: 4}, logger)
+func (wkf *Workflow) ExecuteWithOption(ctx context.Context, cfg *viperx.Viperx, fnRegistry *FnRegistry, retainSkipRange string, opt ExecOption, logger logx.Logger) (*RunReport, error) {
+
+	// check parameters
+	if err := opt.check(); err != nil {
+		return nil, err
+	}
+
+	// strict mode: all the problems of the workflow are fatal
+	if opt.Strict {
+		if err := wkf.Validate(cfg, fnRegistry); err != nil {
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				list.PrettyPrintTable(verr.GetView())
+			}
+			return nil, err
+		}
+	}
+
+	// plan mode: nothing runs
+	if opt.Plan {
+		plan, err := wkf.Plan(cfg, fnRegistry, retainSkipRange, opt, logger)
+		if plan != nil {
+			list.PrettyPrintTable(plan.GetView())
+		}
+		return nil, err
+	}
 
 	// log
 	logger.Infof("🅦 Runing idempotent workflow %q to %s", wkf.Name, wkf.Description)
-	logger.Info("• Tier sequencing:    next tier starts after the previous one completes")
-	logger.Info("• Tier completion:    a tier completes when all its phases completes")
+	if opt.CheckOnly {
+		logger.Info("• Check-only mode:    the check functions run, nothing is applied")
+	}
+	if opt.Scheduler == SchedulerDag {
+		logger.Infof("• Phase sequencing:   a phase starts as soon as all its dependencies complete (max concurrent phases: %s)", opt.maxParallelPhaseView())
+	} else {
+		logger.Info("• Tier sequencing:    next tier starts after the previous one completes")
+		logger.Info("• Tier completion:    a tier completes when all its phases completes")
+	}
 	logger.Info("• Host concurrency:   each phase rus concurently on all hosts")
 	logger.Info("• Node concurrency:   each phase runs (also) concurently on all nodes (when defined)")
 	logger.Info("• Phase completion:   a phase completes (for a host) when all its subsequent node tasks complete")
 
-	// 1 - get the tiers
-	tierList, err := wkf.TopoSortByTier(logger)
+	// 1 - get the tiers to run (expanded, filtered, when conditions evaluated)
+	wkf, tierListFiltered, err := wkf.getRunTierList(cfg, retainSkipRange, opt, logger)
 	if err != nil {
-		return fmt.Errorf("cannot sort tiers: %w", err)
-	}
-
-	// 2 - filter the tier phases according to retainSkipRange
-	tierListFiltered, err := wkf.filterPhase(tierList, retainSkipRange, logger)
-	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 3 - display the workflow
 	phaseView, err := wkf.GetTierView(tierListFiltered, logger)
 	if err != nil {
-		return fmt.Errorf("getting phase table: %w", err)
+		return nil, fmt.Errorf("getting phase table: %w", err)
 	}
 	list.PrettyPrintTable(phaseView)
 
-	// 4 - loop over each tier
+	// 4 - get the state of a previous run (checkpoint/resume)
+	var state *RunState
+	if opt.Checkpoint || opt.Resume {
+		state, err = wkf.LoadRunState(cfg, opt.StateDir)
+		if err != nil {
+			return nil, fmt.Errorf("loading run state: %w", err)
+		}
+		if !opt.Resume {
+			state.Phases = make(map[string]map[string]HostState) // start from scratch
+		}
+		logger.Infof("• Checkpoint:         %s (resume: %v)", state.GetPath(), opt.Resume)
+	}
+
+	// 5 - collect the outcome of each phase and host
+	env := &runEnv{
+		cfg:        cfg,
+		fnRegistry: fnRegistry,
+		opt:        opt,
+		summary:    newRunSummary(state),
+	}
+	env.summary.notifier = newNotifier(wkf.Name, opt.Observers, logger)
+	env.summary.persistOutputs = opt.PersistOutputs
+	env.summary.notify(Event{Type: EventWorkflowStarted})
+
+	// 6 - run the phases according to the scheduler
+	if opt.Scheduler == SchedulerDag {
+		err = wkf.executeDag(ctx, env, tierListFiltered, logger)
+	} else {
+		err = wkf.executeTier(ctx, env, tierListFiltered, logger)
+	}
+
+	// 61 - roll back the completed phases
+	if err != nil && opt.Rollback && !errors.Is(err, ErrCancelled) {
+		if rollbackErr := wkf.rollback(ctx, env, tierListFiltered, logger); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("rollback: %w", rollbackErr))
+		}
+	}
+
+	// 7 - display the run report (attempts and status per phase and host)
+	report := env.summary.getReport(wkf.Name, err)
+	logger.Infof("🅦 Run summary of workflow %q > %s in %s", wkf.Name, report.Status, report.Duration.Round(time.Millisecond))
+	list.PrettyPrintTable(report.GetView())
+	list.PrettyPrintTable(report.GetRecapView())
+	env.summary.notify(Event{Type: EventWorkflowFinished, Status: report.Status, Duration: report.Duration, Err: err, Report: report})
+
+	// 8 - write the run report - the outputs may be secrets
+	if opt.ReportPath != "" {
+		reportFile := report
+		if !opt.PersistOutputs {
+			reportFile = report.redacted()
+		}
+		if reportErr := reportFile.WriteFile(opt.ReportPath); reportErr != nil {
+			logger.Warnf("writing run report: %v", reportErr)
+		} else {
+			logger.Infof("• Run report:         %s", opt.ReportPath)
+		}
+	}
+
+	return report, err
+}
+
+// Description: returns the phases of a run sorted by tier
+//
+// Return:
+// - the workflow once the forEach phases are expanded
+// - the tiers once the phases are filtered (retainSkipRange, opt.Filter) and their when condition evaluated
+//
+// Notes:
+// - shared by a run and a plan: both see the same phases
+func (wkf *Workflow) getRunTierList(cfg *viperx.Viperx, retainSkipRange string, opt ExecOption, logger logx.Logger) (*Workflow, [][]Phase, error) {
+
+	// 1 - expand the forEach phases
+	expanded, err := wkf.expandForEach(cfg, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("expanding forEach phases: %w", err)
+	}
+
+	// 2 - get the tiers
+	tierList, err := expanded.TopoSortByTier(logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot sort tiers: %w", err)
+	}
+
+	// 3 - filter the tier phases according to retainSkipRange
+	tierListFiltered, err := expanded.filterPhase(tierList, retainSkipRange, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 4 - filter the tier phases by name, tag and dependency closure
+	tierListFiltered, err = expanded.applyFilter(tierListFiltered, opt.Filter, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if retainSkipRange != "" || !opt.Filter.isEmpty() {
+		expanded.warnSkippedUpstream(tierListFiltered, logger)
+	}
+
+	// 5 - evaluate the when condition of the phases
+	tierListFiltered, err = applyWhen(tierListFiltered, cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return expanded, tierListFiltered, nil
+}
+
+// Description: runs the phases tier by tier (default scheduler)
+//
+// Notes:
+// - a tier starts once all the phases of the previous tier complete
+func (wkf *Workflow) executeTier(ctx context.Context, env *runEnv, tierListFiltered [][]Phase, logger logx.Logger) error {
+
+	// 1 - loop over each tier
 	nbTier := len(tierListFiltered)
 	for tierId, phaseList := range tierListFiltered {
 
 		tierIdx := tierId + 1
 
-		// 6 - manage goroutines concurrency
+		// 11 - stop scheduling new tiers once the context is done
+		if ctx.Err() != nil {
+			logger.Warnf("🛑 workflow %q cancelled before tier %d:%d", wkf.Name, tierIdx, nbTier)
+			return fmt.Errorf("workflow %q > tier %d > %w", wkf.Name, tierIdx, errCancelled(ctx))
+		}
+
+		// 2 - manage goroutines concurrency
 		nbItem := len(phaseList)
 		var wgTier sync.WaitGroup              // define a WaitGroup instance for each item in the list : wait for all (concurent) goroutines to complete
 		errChPhase := make(chan error, nbItem) // define a channel to collect errors from each goroutine
 		// log
 		logger.Infof("👉 Starting Tier %d:%d:%d concurrent phase(s)", tierIdx, nbTier, nbItem)
-		// 61 - loop over each phases in the tier AND create as many goroutines as phases
+		tierStartTime := time.Now()
+		env.summary.notify(Event{Type: EventTierStarted, Tier: tierIdx, NbTier: nbTier})
+		// 21 - loop over each phases in the tier AND create as many goroutines as phases
 		for _, phase := range phaseList {
+			// stop scheduling new phases once the context is done
+			if ctx.Err() != nil {
+				break
+			}
 			wgTier.Add(1)            // Increment the WaitGroup:counter for each item
 			go func(oneItem Phase) { // create as many goroutine (that will run concurrently) as item AND pass the item as an argument
-				defer wgTier.Done()                            // Decrement the WaitGroup counter - when the goroutine (the phase) completes
-				err := phase.run(ctx, cfg, fnRegistry, logger) // delegate the execution of the phase to this method
-				if err != nil {                                // send goroutines error if any into the chanel
+				defer wgTier.Done()                  // Decrement the WaitGroup counter - when the goroutine (the phase) completes
+				err := oneItem.run(ctx, env, logger) // delegate the execution of the phase to this method
+				if err != nil {                      // send goroutines error if any into the chanel
 					errChPhase <- fmt.Errorf("%w", err)
 				}
 			}(phase) // pass the phase to the goroutine
@@ -100,19 +278,28 @@ func (wkf *Workflow) Execute(ctx context.Context, cfg *viperx.Viperx, fnRegistry
 		wgTier.Wait()     // Wait for all goroutines (one per phase) to complete - done with the help of the WaitGroup:counter
 		close(errChPhase) // close the channel - signal that no more error will be sent
 
-		// 7 - manage goroutines error
-		// 71 - Aggregate goroutines errors
+		// 3 - manage goroutines error
+		// 31 - Aggregate goroutines errors
 		var ErrList []error
 		for e := range errChPhase {
 			ErrList = append(ErrList, e)
 		}
 
-		// 72 - handle error
-		if len(ErrList) > 0 {
-			return fmt.Errorf("errors occurred in tier %d", tierIdx)
+		// 32 - handle cancellation (distinct from failure)
+		var tierErr error
+		if ctx.Err() != nil {
+			logger.Warnf("🛑 workflow %q cancelled in tier %d:%d", wkf.Name, tierIdx, nbTier)
+			tierErr = fmt.Errorf("workflow %q > tier %d > %w", wkf.Name, tierIdx, errCancelled(ctx))
+		} else if len(ErrList) > 0 {
+			// 33 - handle error
+			tierErr = fmt.Errorf("errors occurred in tier %d", tierIdx)
+		}
+		env.summary.notify(Event{Type: EventTierFinished, Tier: tierIdx, NbTier: nbTier, Status: getStatus(tierErr), Duration: time.Since(tierStartTime), Err: tierErr})
+		if tierErr != nil {
+			return tierErr
 		}
 
-		// 8 - handle success
+		// 4 - handle success
 		logger.Infof("• Tier %d complete.", tierIdx)
 	} // tier loop
 
@@ -0,0 +1,109 @@
+package phase2
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abtransitionit/gocore/logx"
+)
+
+// Description: represents the type of a lifecycle event of a workflow run
+type EventType string
+
+const (
+	EventWorkflowStarted   EventType = "WorkflowStarted"   // the phases are about to be scheduled
+	EventTierStarted       EventType = "TierStarted"       // tier scheduler only
+	EventTierFinished      EventType = "TierFinished"      // tier scheduler only
+	EventPhaseStarted      EventType = "PhaseStarted"      // a phase is about to run on its hosts
+	EventPhaseFinished     EventType = "PhaseFinished"     // a phase completed on all its hosts
+	EventPhaseHostStarted  EventType = "PhaseHostStarted"  // a phase is about to run on a host
+	EventPhaseHostFinished EventType = "PhaseHostFinished" // a phase completed (or is skipped) on a host
+	EventWorkflowFinished  EventType = "WorkflowFinished"  // the run completed (Report is set)
+)
+
+// Description: represents a lifecycle event of a workflow run
+//
+// Notes:
+// - only the fields that make sense for the type of event are set
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Workflow string
+	Tier     int           // 1-based (TierStarted, TierFinished)
+	NbTier   int           // TierStarted, TierFinished
+	Phase    string        // Phase*, PhaseHost*
+	Host     string        // PhaseHost*
+	Attempt  int           // PhaseHostFinished
+	Status   string        // *Finished: success, failed, cancelled, ... (see HostReport.Status for a host)
+	Duration time.Duration // *Finished
+	Err      error         // *Finished
+	Report   *RunReport    // WorkflowFinished
+}
+
+// Description: represents a component notified of the lifecycle events of a workflow run
+//
+// Notes:
+// - eg. a progress UI, a notification, metrics, an audit log
+// - the events of a run are delivered one at a time (never concurrently) in the order they occur
+// - OnEvent must return quickly: it blocks the goroutine that emits the event
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// Description: adapts a function into an Observer
+//
+// Example Usage:
+//
+//	opt := phase2.ExecOption{Observers: []phase2.Observer{
+//		phase2.ObserverFunc(func(event phase2.Event) {
+//			if event.Type == phase2.EventPhaseHostFinished {
+//				fmt.Printf("%s > %s > %s\n", event.Phase, event.Host, event.Status)
+//			}
+//		}),
+//	}}
+type ObserverFunc func(event Event)
+
+// Description: calls the function
+func (fn ObserverFunc) OnEvent(event Event) {
+	fn(event)
+}
+
+// Description: delivers the events of a workflow run to the observers
+type notifier struct {
+	mu           sync.Mutex
+	workflowName string
+	observerList []Observer
+	logger       logx.Logger
+}
+
+// Description: constructor that returns an instance of notifier
+func newNotifier(workflowName string, observerList []Observer, logger logx.Logger) *notifier {
+	return &notifier{workflowName: workflowName, observerList: observerList, logger: logger}
+}
+
+// Description: delivers an event to each observer
+//
+// Notes:
+// - the Time and Workflow fields are set
+// - a panicking observer is logged and does not stop the workflow
+func (n *notifier) notify(event Event) {
+	if n == nil || len(n.observerList) == 0 {
+		return
+	}
+	event.Time = time.Now()
+	event.Workflow = n.workflowName
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, observer := range n.observerList {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					n.logger.Warnf("observer %T panicked on %s: %v", observer, event.Type, fmt.Sprint(r))
+				}
+			}()
+			observer.OnEvent(event)
+		}()
+	}
+}
@@ -0,0 +1,59 @@
+package phase2
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestObserver
+func TestObserver(t *testing.T) {
+	// create inputs for the test : a workflow a -> b on 1 host, a panicking observer and a recording one
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	cfg := &viperx.Viperx{Viper: v}
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		return true, nil
+	}))
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a": {FnAlias: "work", Node: "nodes"},
+		"b": {FnAlias: "work", Node: "nodes", Dependency: []string{"a"}},
+	}}
+	var eventList []string
+	var lastEvent Event
+	panicking := ObserverFunc(func(event Event) { panic("boom") })
+	recording := ObserverFunc(func(event Event) {
+		assert.Equal(t, "wkf", event.Workflow)
+		assert.False(t, event.Time.IsZero())
+		eventList = append(eventList, fmt.Sprintf("%s:%d:%s:%s:%s", event.Type, event.Tier, event.Phase, event.Host, event.Status))
+		lastEvent = event
+	})
+
+	// the events are delivered in order - a panicking observer does not stop the run
+	report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{Observers: []Observer{panicking, recording}}, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"WorkflowStarted:0:::",
+		"TierStarted:1:::",
+		"PhaseStarted:0:a::",
+		"PhaseHostStarted:0:a:h1:",
+		"PhaseHostFinished:0:a:h1:success",
+		"PhaseFinished:0:a::success",
+		"TierFinished:1:::success",
+		"TierStarted:2:::",
+		"PhaseStarted:0:b::",
+		"PhaseHostStarted:0:b:h1:",
+		"PhaseHostFinished:0:b:h1:success",
+		"PhaseFinished:0:b::success",
+		"TierFinished:2:::success",
+		"WorkflowFinished:0:::success",
+	}, eventList)
+	assert.Equal(t, report, lastEvent.Report)
+}
@@ -0,0 +1,251 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Description: the prefix of a param that references the output of a phase
+//
+// Notes:
+// - syntax: outputs.<phase>.<host>.<key> (eg. outputs.initCp.cp1.joinToken)
+const outputParamPrefix = "outputs."
+
+// Description: denotes a param that references an output that was not published
+var ErrOutputNotFound = errors.New("output not found")
+
+// Description: the value written in place of an output into the state file and the report file (see ExecOption.PersistOutputs)
+const redactedOutput = "<redacted>"
+
+// Description: returns a copy of outputs whose values are redacted (the keys are kept)
+func redactOutputs(outputMap map[string]any) map[string]any {
+	if len(outputMap) == 0 {
+		return nil
+	}
+	redacted := make(map[string]any, len(outputMap))
+	for key := range outputMap {
+		redacted[key] = redactedOutput
+	}
+	return redacted
+}
+
+// Description: reports whether outputs were redacted before being persisted
+func isRedacted(outputMap map[string]any) bool {
+	for _, value := range outputMap {
+		if value == redactedOutput {
+			return true
+		}
+	}
+	return false
+}
+
+// Description: represents the outputs published by the phases of a workflow run
+//
+// Notes:
+// - run-scoped: created once per run
+// - written concurrently by the goroutines of the phases
+type OutputStore struct {
+	mu        sync.Mutex
+	outputMap map[string]map[string]map[string]any // phase > host > key > value
+}
+
+// Description: constructor that returns an instance of OutputStore
+func newOutputStore() *OutputStore {
+	return &OutputStore{outputMap: make(map[string]map[string]map[string]any)}
+}
+
+// Description: stores an output of a phase for a host
+func (store *OutputStore) set(phaseName, hostName, key string, value any) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.outputMap[phaseName] == nil {
+		store.outputMap[phaseName] = make(map[string]map[string]any)
+	}
+	if store.outputMap[phaseName][hostName] == nil {
+		store.outputMap[phaseName][hostName] = make(map[string]any)
+	}
+	store.outputMap[phaseName][hostName][key] = value
+}
+
+// Description: returns an output of a phase for a host
+func (store *OutputStore) Get(phaseName, hostName, key string) (any, bool) {
+	if store == nil {
+		return nil, false
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	value, ok := store.outputMap[phaseName][hostName][key]
+	return value, ok
+}
+
+// Description: returns the outputs of a phase for a host (a copy)
+func (store *OutputStore) getHost(phaseName, hostName string) map[string]any {
+	if store == nil {
+		return nil
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	hostMap := store.outputMap[phaseName][hostName]
+	if len(hostMap) == 0 {
+		return nil
+	}
+	outputMap := make(map[string]any, len(hostMap))
+	for key, value := range hostMap {
+		outputMap[key] = value
+	}
+	return outputMap
+}
+
+// Description: represents the phase and host a PhaseFnCtx runs for
+//
+// Notes:
+// - injected into the ctx passed to the function
+type outputScope struct {
+	store     *OutputStore
+	phaseName string
+	hostName  string
+}
+
+// Description: custom key type of the output scope in a context
+type outputScopeKey struct{}
+
+// Description: returns a ctx that allows a PhaseFnCtx to publish its outputs
+func withOutputScope(ctx context.Context, store *OutputStore, phaseName, hostName string) context.Context {
+	return context.WithValue(ctx, outputScopeKey{}, &outputScope{store: store, phaseName: phaseName, hostName: hostName})
+}
+
+// Description: publishes a named output of the running phase for the current host
+//
+// Parameters:
+// - ctx: the ctx received by the PhaseFnCtx
+// - key: the name of the output (eg. joinToken)
+// - value: the value of the output
+//
+// Notes:
+// - downstream phases consume it with the param outputs.<phase>.<host>.<key>
+// - only a PhaseFnCtx can publish outputs (a legacy PhaseFn has no ctx)
+//
+// Example Usage:
+//
+//	func InitCp(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+//		// ...
+//		if err := phase2.SetOutput(ctx, "joinToken", token); err != nil {
+//			return false, err
+//		}
+//		return true, nil
+//	}
+func SetOutput(ctx context.Context, key string, value any) error {
+	scope, ok := ctx.Value(outputScopeKey{}).(*outputScope)
+	if !ok || scope.store == nil {
+		return fmt.Errorf("setting output %q: ctx is not the ctx of a running phase", key)
+	}
+	if key == "" || strings.Contains(key, ".") {
+		return fmt.Errorf("setting output %q: the key must be non empty and must not contain a dot", key)
+	}
+	scope.store.set(scope.phaseName, scope.hostName, key, value)
+	return nil
+}
+
+// Description: returns an output published by a phase for a host
+//
+// Notes:
+// - allows a PhaseFnCtx to read upstream outputs without declaring them as params
+func GetOutput(ctx context.Context, phaseName, hostName, key string) (any, bool) {
+	scope, ok := ctx.Value(outputScopeKey{}).(*outputScope)
+	if !ok {
+		return nil, false
+	}
+	return scope.store.Get(phaseName, hostName, key)
+}
+
+// Description: represents a param that references the output of a phase
+type outputRef struct {
+	phaseName string
+	hostName  string
+	key       string
+}
+
+// Description: parses a param that references the output of a phase
+//
+// Parameters:
+// - param: the param (eg. outputs.initCp.cp1.joinToken)
+// - phaseMap: the phases of the workflow (a phase name or a host name may contain a dot)
+//
+// Return:
+// - false if the param does not reference an output
+// - an error if the param references an output but cannot be parsed
+func parseOutputRef(param string, phaseMap map[string]Phase) (outputRef, bool, error) {
+	if !strings.HasPrefix(param, outputParamPrefix) {
+		return outputRef{}, false, nil
+	}
+	rest := strings.TrimPrefix(param, outputParamPrefix)
+
+	// the key is the last segment
+	idx := strings.LastIndex(rest, ".")
+	if idx <= 0 {
+		return outputRef{}, true, fmt.Errorf("invalid output reference %q (expected outputs.<phase>.<host>.<key>)", param)
+	}
+	key := rest[idx+1:]
+	phaseHost := rest[:idx]
+
+	// the phase is the longest phase name that prefixes the rest
+	nameList := make([]string, 0, len(phaseMap))
+	for name := range phaseMap {
+		nameList = append(nameList, name)
+	}
+	sort.Slice(nameList, func(i, j int) bool { return len(nameList[i]) > len(nameList[j]) })
+	for _, name := range nameList {
+		if strings.HasPrefix(phaseHost, name+".") && len(phaseHost) > len(name)+1 {
+			return outputRef{phaseName: name, hostName: phaseHost[len(name)+1:], key: key}, true, nil
+		}
+	}
+
+	// unknown phase: best effort (first segment)
+	parts := strings.SplitN(phaseHost, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || key == "" {
+		return outputRef{}, true, fmt.Errorf("invalid output reference %q (expected outputs.<phase>.<host>.<key>)", param)
+	}
+	return outputRef{phaseName: parts[0], hostName: parts[1], key: key}, true, nil
+}
+
+// Description: returns the phases a phase depends on (directly or not)
+func (wf *Workflow) getUpstreamSet(phaseName string) map[string]bool {
+	upstreamSet := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		for _, dep := range wf.Phases[name].Dependency {
+			if !upstreamSet[dep] {
+				upstreamSet[dep] = true
+				visit(dep)
+			}
+		}
+	}
+	visit(phaseName)
+	return upstreamSet
+}
+
+// Description: returns the value of a param that references the output of a phase
+//
+// Notes:
+// - a phase name or a host name may contain a dot: each split is looked up in the store
+func (store *OutputStore) resolve(param string) (any, error) {
+	rest := strings.TrimPrefix(param, outputParamPrefix)
+	idx := strings.LastIndex(rest, ".")
+	if idx <= 0 {
+		return nil, fmt.Errorf("invalid output reference %q (expected outputs.<phase>.<host>.<key>)", param)
+	}
+	key, phaseHost := rest[idx+1:], rest[:idx]
+	for i := 0; i < len(phaseHost); i++ {
+		if phaseHost[i] != '.' {
+			continue
+		}
+		if value, ok := store.Get(phaseHost[:i], phaseHost[i+1:], key); ok {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("param %q: %w (the producer did not publish it)", param, ErrOutputNotFound)
+}
@@ -0,0 +1,202 @@
+package phase2
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestParseOutputRef
+func TestParseOutputRef(t *testing.T) {
+	// create inputs for the test : phases whose name may contain a dot
+	phaseMap := map[string]Phase{"initCp": {}, "prep.os": {}}
+
+	// Define test cases.
+	tests := []struct {
+		name         string    // test case name
+		param        string    // the input
+		want         outputRef // expected reference
+		wantIsOutput bool      // expected: the param references an output
+		wantErr      bool      // expected error
+	}{
+		{name: "Case 1: config param", param: "version"},
+		{name: "Case 2: output", param: "outputs.initCp.cp1.joinToken", want: outputRef{phaseName: "initCp", hostName: "cp1", key: "joinToken"}, wantIsOutput: true},
+		{name: "Case 3: phase name with a dot", param: "outputs.prep.os.cp1.url", want: outputRef{phaseName: "prep.os", hostName: "cp1", key: "url"}, wantIsOutput: true},
+		{name: "Case 4: host name with a dot", param: "outputs.initCp.cp1.lab.local.url", want: outputRef{phaseName: "initCp", hostName: "cp1.lab.local", key: "url"}, wantIsOutput: true},
+		{name: "Case 5: unknown phase", param: "outputs.zz.cp1.url", want: outputRef{phaseName: "zz", hostName: "cp1", key: "url"}, wantIsOutput: true},
+		{name: "Case 6: no host", param: "outputs.initCp.url", wantIsOutput: true, wantErr: true},
+		{name: "Case 7: no key", param: "outputs.initCp", wantIsOutput: true, wantErr: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isOutput, err := parseOutputRef(tt.param, phaseMap)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.Equal(t, tt.wantIsOutput, isOutput)
+		})
+	}
+}
+
+// Name: TestOutputStoreResolve
+func TestOutputStoreResolve(t *testing.T) {
+	// create inputs for the test : outputs of phases and hosts whose name may contain a dot
+	store := newOutputStore()
+	store.set("initCp", "cp1", "joinToken", "abc")
+	store.set("prep.os", "cp1.lab", "url", "http://x")
+	store.set("list", "h1", "items", []string{"a", "b"})
+
+	// Define test cases.
+	tests := []struct {
+		name    string // test case name
+		param   string // the input
+		want    any    // expected value
+		wantErr error  // expected error (nil if none)
+	}{
+		{name: "Case 1: output", param: "outputs.initCp.cp1.joinToken", want: "abc"},
+		{name: "Case 2: phase and host names with a dot", param: "outputs.prep.os.cp1.lab.url", want: "http://x"},
+		{name: "Case 3: list", param: "outputs.list.h1.items", want: []string{"a", "b"}},
+		{name: "Case 4: not published", param: "outputs.initCp.cp2.joinToken", wantErr: ErrOutputNotFound},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.resolve(tt.param)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Name: TestCheckOutputRef
+func TestCheckOutputRef(t *testing.T) {
+	// create inputs for the test : a workflow initCp -> join and an independent phase
+	v := viper.New()
+	v.Set("cps", []string{"cp1"})
+	v.Set("workers", []string{"w1", "w2"})
+	cfg := &viperx.Viperx{Viper: v}
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"initCp": {Node: "cps"},
+		"join":   {Node: "workers", Dependency: []string{"initCp"}},
+		"other":  {Node: "workers"},
+	}}
+
+	// Define test cases.
+	tests := []struct {
+		name      string // test case name
+		phaseName string // the input : the consumer
+		param     string // the input
+		want      string // expected problem ("" for none)
+	}{
+		{name: "Case 1: upstream producer", phaseName: "join", param: "outputs.initCp.cp1.joinToken"},
+		{name: "Case 2: producer is not upstream", phaseName: "other", param: "outputs.initCp.cp1.joinToken", want: "not an upstream dependency"},
+		{name: "Case 3: unknown producer", phaseName: "join", param: "outputs.zz.cp1.joinToken", want: "does not exist"},
+		{name: "Case 4: host not a host of the producer", phaseName: "join", param: "outputs.initCp.w1.joinToken", want: `references host "w1" that is not a host of phase "initCp"`},
+		{name: "Case 5: invalid reference", phaseName: "join", param: "outputs.initCp", want: "invalid output reference"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verr := &ValidationError{WorkflowName: wf.Name}
+			ref, _, err := parseOutputRef(tt.param, wf.Phases)
+			wf.checkOutputRef(tt.phaseName, tt.param, ref, err, cfg, verr)
+			if tt.want == "" {
+				assert.Empty(t, verr.Problems)
+				return
+			}
+			if assert.Len(t, verr.Problems, 1) {
+				assert.Equal(t, "param", verr.Problems[0].Kind)
+				assert.Contains(t, verr.Problems[0].Message, tt.want)
+			}
+		})
+	}
+}
+
+// Name: TestPersistOutputs
+func TestPersistOutputs(t *testing.T) {
+	// create inputs for the test : a workflow initCp -> join where initCp publishes a secret
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("cps", []string{"cp1"})
+	cfg := &viperx.Viperx{Viper: v}
+	secret := "s3cr3t-join-token"
+
+	// Define test cases.
+	tests := []struct {
+		name           string // test case name
+		persistOutputs bool   // the input
+		wantSecret     bool   // expected: the secret is written into the files
+		wantNbInitCp   int    // expected number of runs of initCp after a resumed run
+	}{
+		{name: "Case 1: outputs redacted", persistOutputs: false, wantSecret: false, wantNbInitCp: 2},
+		{name: "Case 2: outputs persisted", persistOutputs: true, wantSecret: true, wantNbInitCp: 1},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nbInitCp := 0
+			var gotToken any
+			registry := NewFnRegistry()
+			assert.NoError(t, registry.AddCtx("wkf", "initCp", func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+				nbInitCp++
+				return true, SetOutput(ctx, "joinToken", secret)
+			}))
+			assert.NoError(t, registry.Add("wkf", "join", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+				gotToken = params[0][0]
+				return true, nil
+			}))
+			wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+				"initCp": {WkfName: "wkf", FnAlias: "initCp", Node: "cps"},
+				"join":   {WkfName: "wkf", FnAlias: "join", Node: "cps", Param: []string{"outputs.initCp.cp1.joinToken"}, Dependency: []string{"initCp"}},
+			}}
+			dir := t.TempDir()
+			opt := ExecOption{Checkpoint: true, StateDir: filepath.Join(dir, "state"), ReportPath: filepath.Join(dir, "report.json"), PersistOutputs: tt.persistOutputs}
+
+			// the first run publishes the secret - the report returned keeps the value
+			report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", opt, logger)
+			assert.NoError(t, err)
+			assert.Equal(t, secret, gotToken)
+			assert.Equal(t, secret, report.Hosts[0].Outputs["joinToken"])
+
+			// the files are readable by their owner only and contain the secret only when asked
+			statePath, err := wf.GetStatePath(cfg, opt.StateDir)
+			assert.NoError(t, err)
+			for _, path := range []string{statePath, opt.ReportPath} {
+				info, err := os.Stat(path)
+				if assert.NoError(t, err) {
+					assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), path)
+				}
+				data, err := os.ReadFile(path)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantSecret, strings.Contains(string(data), secret), path)
+				assert.Contains(t, string(data), "joinToken", path)
+			}
+
+			// a resumed run gets the secret: from the state, or by running the producer again
+			gotToken = nil
+			opt.Resume = true
+			_, err = wf.ExecuteWithOption(context.Background(), cfg, registry, "", opt, logger)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNbInitCp, nbInitCp)
+		})
+	}
+}
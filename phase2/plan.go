@@ -0,0 +1,187 @@
+package phase2
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+)
+
+// Description: the status of a phase that cannot be resolved in non-strict mode (a real run skips it)
+const statusSkippedUnresolved = "skipped (unresolved)"
+
+// Description: represents what a run would do for a phase
+type PlanStep struct {
+	Tier     int      `json:"tier"` // 1-based
+	Phase    string   `json:"phase"`
+	Group    string   `json:"group,omitempty"`
+	Hosts    []string `json:"hosts"`
+	Params   []string `json:"params"`             // key=value (an output of an upstream phase is a placeholder: <outputs.phase.host.key>)
+	Function string   `json:"function,omitempty"` // package.name of the GO function
+	Check    string   `json:"check,omitempty"`    // package.name of the check function
+	Undo     string   `json:"undo,omitempty"`     // package.name of the undo function
+	Status   string   `json:"status"`             // run, skipped (condition), skipped (unresolved), error
+	Error    string   `json:"error,omitempty"`
+}
+
+// Description: represents what a run of a workflow would do
+//
+// Notes:
+// - returned by Workflow.Plan
+type RunPlan struct {
+	WorkflowName string     `json:"workflowName"`
+	Steps        []PlanStep `json:"steps"` // sorted by tier
+}
+
+// Description: returns what a run of the workflow would do, without running any phase
+//
+// Parameters:
+// - cfg, fnRegistry, retainSkipRange, opt: the same as Workflow.ExecuteWithOption
+//
+// Return:
+// - the plan: the tier, the hosts, the resolved params and the GO functions of each phase to run
+// - an error that lists the phases that cannot be resolved (strict mode)
+//
+// Notes:
+// - the phases are expanded, filtered and their when condition evaluated as in a real run
+// - a phase is resolved as in a real run: the same errors (host, param, function, check function)
+// - a phase that cannot be resolved is skipped (unresolved) unless opt.Strict (as in a real run)
+// - the undo function is resolved when opt.Rollback is set
+// - a phase skipped by its when condition is not resolved (as in a real run)
+// - no PhaseFn is called and no state is read or written
+//
+// Example Usage:
+//
+//	plan, err := workflow.Plan(cfg, &fnRegistry, retainSkipRange, phase2.ExecOption{}, logger)
+//	list.PrettyPrintTable(plan.GetView())
+func (wkf *Workflow) Plan(cfg *viperx.Viperx, fnRegistry *FnRegistry, retainSkipRange string, opt ExecOption, logger logx.Logger) (*RunPlan, error) {
+
+	// log
+	logger.Infof("🅦 Planning workflow %q to %s", wkf.Name, wkf.Description)
+
+	// 1 - get the tiers to run
+	wkf, tierList, err := wkf.getRunTierList(cfg, retainSkipRange, opt, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2 - resolve each phase (no outputs: a reference to an output is a placeholder)
+	env := &runEnv{cfg: cfg, fnRegistry: fnRegistry, opt: opt}
+	plan := &RunPlan{WorkflowName: wkf.Name}
+	var errList []error
+	for tierIdx, tier := range tierList {
+		for _, phase := range tier {
+			step, err := phase.plan(env, logger)
+			step.Tier = tierIdx + 1
+			if err != nil {
+				step.Status = "error"
+				step.Error = err.Error()
+				errList = append(errList, fmt.Errorf("phase %s > cannot be resolved: %w", phase.Name, err))
+			}
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+
+	// 3 - handle errors
+	if len(errList) > 0 {
+		logger.Errorf("❌ workflow %q > %d phase(s) cannot be resolved", wkf.Name, len(errList))
+		return plan, errors.Join(errList...)
+	}
+	logger.Infof("🅦 Plan of workflow %q > %d phase(s) in %d tier(s)", wkf.Name, len(plan.Steps), len(tierList))
+	return plan, nil
+}
+
+// Description: returns what a run would do for a phase
+func (phase *Phase) plan(env *runEnv, logger logx.Logger) (PlanStep, error) {
+	step := PlanStep{Phase: phase.Name, Group: phase.group, Status: "run"}
+
+	// 1 - the phase is not run (eg. its when condition is false)
+	if phase.skipReason != "" {
+		step.Status = phase.skipReason
+		if hostList, err := getHostList(phase.Node, env.cfg); err == nil {
+			step.Hosts = hostList
+		}
+		return step, nil
+	}
+
+	// 2 - resolve the hosts, the params and the functions
+	resolution, err := phase.resolve(env, logger)
+	step.Hosts = resolution.hostList
+	if err != nil && !env.opt.Strict {
+		// a real run skips the phase (see Phase.skip)
+		step.Status = statusSkippedUnresolved
+		step.Error = err.Error()
+		return step, nil
+	}
+	if err != nil {
+		return step, err
+	}
+	for i, key := range phase.Param {
+		if i < len(resolution.paramList) {
+			step.Params = append(step.Params, fmt.Sprintf("%s=%s", key, getParamView(resolution.paramList[i])))
+		}
+	}
+	step.Function = getFnView(resolution.fn.origin, logger)
+	if phase.Check != "" {
+		step.Check = getFnView(resolution.check.origin, logger)
+	}
+
+	// 3 - resolve the undo function (only run on rollback)
+	if phase.Undo != "" {
+		undoEntry, err := getPhaseFn(phase.WkfName, phase.Undo, env.fnRegistry)
+		switch {
+		case err == nil:
+			step.Undo = getFnView(undoEntry.origin, logger)
+		case env.opt.Rollback:
+			return step, fmt.Errorf("undo > %w", err)
+		default:
+			step.Undo = phase.Undo + " (not registered)"
+		}
+	}
+	return step, nil
+}
+
+// Description: returns a resolved param as a string to be displayed
+func getParamView(param []any) string {
+	if len(param) == 1 {
+		return fmt.Sprint(param[0])
+	}
+	return fmt.Sprint(param)
+}
+
+// Description: returns the package and the name of a GO function as a string to be displayed
+func getFnView(fn any, logger logx.Logger) string {
+	goFnPkg, goFnName := describeFn(fn, logger)
+	return goFnPkg + "." + goFnName
+}
+
+// Description: returns a view of the plan
+func (plan *RunPlan) GetView() string {
+	var b strings.Builder
+	b.WriteString("Tier\tPhase\tHost\tParam\tFunction\tStatus\n")
+	for _, step := range plan.Steps {
+		host := "none"
+		if len(step.Hosts) > 0 {
+			host = strings.Join(step.Hosts, ", ")
+		}
+		param := "none"
+		if len(step.Params) > 0 {
+			param = strings.Join(step.Params, ", ")
+		}
+		function := step.Function
+		if function == "" {
+			function = "none"
+		}
+		if step.Check != "" {
+			function += " (check: " + step.Check + ")"
+		}
+		status := step.Status
+		if step.Error != "" {
+			status += ": " + step.Error
+		}
+		fmt.Fprintf(&b, "%d\t%s\t%s\t%s\t%s\t%s\n", step.Tier, step.Phase, host, param, function, status)
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package phase2
+
+import (
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestPlan
+func TestPlan(t *testing.T) {
+	// create inputs for the test : a config, a registry whose function must not be called and a workflow a -> b
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1", "h2"})
+	v.Set("version", "1.2")
+	cfg := &viperx.Viperx{Viper: v}
+	registry := NewFnRegistry()
+	called := false
+	assert.NoError(t, registry.Add("wkf", "install", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		called = true
+		return true, nil
+	}))
+
+	// Define test cases.
+	tests := []struct {
+		name       string // test case name
+		fnAlias    string // the input : the function of phase b
+		param      string // the input : the param of phase b
+		strict     bool   // the input : ExecOption.Strict
+		wantParams []string
+		wantStatus string // expected status of phase b
+		wantErr    bool   // expected error
+	}{
+		{name: "Case 1: config param", fnAlias: "install", param: "version", wantParams: []string{"version=1.2"}, wantStatus: "run"},
+		{name: "Case 2: output placeholder", fnAlias: "install", param: "outputs.a.url", wantParams: []string{"outputs.a.url=<outputs.a.url>"}, wantStatus: "run"},
+		{name: "Case 3: function not registered - strict", fnAlias: "missing", param: "version", strict: true, wantStatus: "error", wantErr: true},
+		{name: "Case 4: function not registered - skipped as in a run", fnAlias: "missing", param: "version", wantStatus: statusSkippedUnresolved},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+				"a": {WkfName: "wkf", FnAlias: "install", Node: "nodes"},
+				"b": {WkfName: "wkf", FnAlias: tt.fnAlias, Node: "nodes", Param: []string{tt.param}, Dependency: []string{"a"}},
+			}}
+			plan, err := wf.Plan(cfg, registry, "", ExecOption{Strict: tt.strict}, logger)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if assert.NotNil(t, plan) && assert.Len(t, plan.Steps, 2) {
+				step := plan.Steps[1]
+				assert.Equal(t, 2, step.Tier)
+				assert.Equal(t, []string{"h1", "h2"}, step.Hosts)
+				assert.Equal(t, tt.wantStatus, step.Status)
+				assert.Equal(t, tt.wantParams, step.Params)
+			}
+		})
+	}
+	assert.False(t, called)
+}
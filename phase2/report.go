@@ -0,0 +1,497 @@
+package phase2
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Description: represents the outcome of a phase on a host
+type HostReport struct {
+	Phase         string         `json:"phase"`
+	Host          string         `json:"host"`
+	StartTime     time.Time      `json:"startTime"`
+	EndTime       time.Time      `json:"endTime"`
+	Duration      time.Duration  `json:"duration"` // nanoseconds
+	Attempt       int            `json:"attempt"`
+	Status        string         `json:"status"` // success, unchanged, would change, success (resumed), skipped (aborted), skipped (condition), skipped (no check), failed, timeout, cancelled
+	Error         string         `json:"error,omitempty"`
+	Outputs       map[string]any `json:"outputs,omitempty"`  // the outputs published by the phase for the host
+	Rollback      string         `json:"rollback,omitempty"` // the status of the undo function run after a workflow failure (success, failed, timeout, cancelled)
+	RollbackError string         `json:"rollbackError,omitempty"`
+}
+
+// Description: represents the outcome of a workflow run
+//
+// Notes:
+// - returned by Workflow.ExecuteWithOption
+// - can be serialized to JSON (ToJson) and to JUnit XML (ToJunit) so CI can show per-host failures
+type RunReport struct {
+	WorkflowName string        `json:"workflowName"`
+	StartTime    time.Time     `json:"startTime"`
+	EndTime      time.Time     `json:"endTime"`
+	Duration     time.Duration `json:"duration"` // nanoseconds
+	Status       string        `json:"status"`   // success, failed, cancelled
+	Error        string        `json:"error,omitempty"`
+	Hosts        []HostReport  `json:"hosts"`  // sorted by phase then host
+	Phases       []PhaseReport `json:"phases"` // sorted by phase
+}
+
+// Description: represents the number of hosts per outcome of a phase (like the Ansible recap)
+//
+// Notes:
+// - Changed: the phase was applied (success) or would be applied (check-only mode)
+// - Unchanged: the host was already in the desired state (see Phase.Check)
+// - Skipped: the phase did not run on the host (resumed, aborted, cancelled, condition, no check)
+type PhaseReport struct {
+	Phase     string `json:"phase"`
+	Changed   int    `json:"changed"`
+	Unchanged int    `json:"unchanged"`
+	Failed    int    `json:"failed"`
+	Skipped   int    `json:"skipped"`
+}
+
+// Description: collects per phase, per host outcomes of a workflow run
+//
+// Notes:
+// - written concurrently by the goroutines of the phases
+// - when a state is defined, each outcome is also persisted (checkpoint)
+type runSummary struct {
+	mu             sync.Mutex
+	startTime      time.Time
+	outcomeMap     map[string]map[string]HostReport // phase > host > outcome
+	state          *RunState                        // optional
+	outputs        *OutputStore                     // the outputs published by the phases
+	persistOutputs bool                             // persist the values of the outputs into the state (see ExecOption.PersistOutputs)
+	notifier       *notifier                        // optional: notifies the observers of each outcome
+}
+
+// Description: constructor that returns an instance of runSummary
+//
+// Notes:
+// - the outputs of the phase/host pairs that succeeded in a previous run are restored from the state (resume)
+func newRunSummary(state *RunState) *runSummary {
+	summary := &runSummary{
+		startTime:  time.Now(),
+		outcomeMap: make(map[string]map[string]HostReport),
+		state:      state,
+		outputs:    newOutputStore(),
+	}
+	if state != nil {
+		for phaseName, hostMap := range state.Phases {
+			for hostName, hostState := range hostMap {
+				if !state.isSuccess(phaseName, hostName) {
+					continue
+				}
+				for key, value := range hostState.Outputs {
+					summary.outputs.set(phaseName, hostName, key, value)
+				}
+			}
+		}
+	}
+	return summary
+}
+
+// Description: the status of a host already in the desired state (its check function returns true)
+const statusUnchanged = "unchanged"
+
+// Description: the status of a host not in the desired state in check-only mode
+const statusWouldChange = "would change"
+
+// Description: the status of a host of a phase with no check function in check-only mode
+const statusSkippedNoCheck = "skipped (no check)"
+
+// Description: reports whether a status denotes a host in the desired state at the end of its execution
+func isSuccessStatus(status string) bool {
+	return status == "success" || status == statusUnchanged
+}
+
+// Description: returns the status of a host execution from its error
+func getStatus(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrCancelled):
+		return "cancelled"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	default:
+		return "failed"
+	}
+}
+
+// Description: records the outcome of a phase on a host
+//
+// Return:
+// - an error if the outcome cannot be persisted into the state
+func (summary *runSummary) record(phaseName, hostName string, startTime time.Time, attempt int, err error) error {
+	return summary.recordStatus(phaseName, hostName, startTime, attempt, getStatus(err), err)
+}
+
+// Description: records the outcome of a phase on a host with an explicit status (eg. unchanged)
+//
+// Return:
+// - an error if the outcome cannot be persisted into the state
+func (summary *runSummary) recordStatus(phaseName, hostName string, startTime time.Time, attempt int, status string, err error) error {
+	if summary == nil {
+		return nil
+	}
+	endTime := time.Now()
+	hostReport := HostReport{
+		Phase:     phaseName,
+		Host:      hostName,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Duration:  endTime.Sub(startTime),
+		Attempt:   attempt,
+		Status:    status,
+		Outputs:   summary.outputs.getHost(phaseName, hostName),
+	}
+	if err != nil {
+		hostReport.Error = err.Error()
+	}
+	summary.setOutcome(hostReport)
+	summary.notify(Event{Type: EventPhaseHostFinished, Phase: phaseName, Host: hostName, Attempt: attempt, Status: status, Duration: hostReport.Duration, Err: err})
+
+	// persist
+	outputMap := hostReport.Outputs
+	if !summary.persistOutputs {
+		outputMap = redactOutputs(outputMap)
+	}
+	return summary.state.set(phaseName, hostName, HostState{
+		Status:    hostReport.Status,
+		Attempt:   attempt,
+		Error:     hostReport.Error,
+		Outputs:   outputMap,
+		UpdatedAt: endTime,
+	})
+}
+
+// Description: records a host on which the phase is not run
+//
+// Parameters:
+// - status: the reason (eg. "success (resumed)", "skipped (aborted)")
+func (summary *runSummary) recordSkipped(phaseName, hostName, status string) {
+	if summary == nil {
+		return
+	}
+	now := time.Now()
+	summary.setOutcome(HostReport{Phase: phaseName, Host: hostName, StartTime: now, EndTime: now, Status: status, Outputs: summary.outputs.getHost(phaseName, hostName)})
+	summary.notify(Event{Type: EventPhaseHostFinished, Phase: phaseName, Host: hostName, Status: status})
+}
+
+// Description: reports whether a phase already succeeded on a host in a previous run
+func (summary *runSummary) isResumed(phaseName, hostName string) bool {
+	return summary != nil && summary.state.isSuccess(phaseName, hostName)
+}
+
+// Description: records the outcome of the undo function of a phase on a host
+//
+// Return:
+// - an error if the outcome cannot be persisted into the state
+//
+// Notes:
+// - a host rolled back successfully is no longer successful in the state: a resumed run executes the phase again
+func (summary *runSummary) recordRollback(phaseName, hostName string, err error) error {
+	if summary == nil {
+		return nil
+	}
+	status := getStatus(err)
+	summary.mu.Lock()
+	hostReport := summary.outcomeMap[phaseName][hostName]
+	hostReport.Rollback = status
+	if err != nil {
+		hostReport.RollbackError = err.Error()
+	}
+	summary.outcomeMap[phaseName][hostName] = hostReport
+	summary.mu.Unlock()
+
+	// persist
+	if err != nil {
+		return nil
+	}
+	return summary.state.set(phaseName, hostName, HostState{
+		Status:    "rolled back",
+		Attempt:   hostReport.Attempt,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// Description: returns the hosts on which a phase succeeded during the run (resumed hosts excluded)
+func (summary *runSummary) getSuccessHostList(phaseName string) []string {
+	if summary == nil {
+		return nil
+	}
+	summary.mu.Lock()
+	defer summary.mu.Unlock()
+	var hostList []string
+	for hostName, hostReport := range summary.outcomeMap[phaseName] {
+		if hostReport.Status == "success" {
+			hostList = append(hostList, hostName)
+		}
+	}
+	sort.Strings(hostList)
+	return hostList
+}
+
+// Description: notifies the observers of the run (if any)
+func (summary *runSummary) notify(event Event) {
+	if summary == nil {
+		return
+	}
+	summary.notifier.notify(event)
+}
+
+// Description: returns the outputs published by the phases of the run
+func (summary *runSummary) getOutputStore() *OutputStore {
+	if summary == nil {
+		return nil
+	}
+	return summary.outputs
+}
+
+func (summary *runSummary) setOutcome(hostReport HostReport) {
+	summary.mu.Lock()
+	defer summary.mu.Unlock()
+	if summary.outcomeMap[hostReport.Phase] == nil {
+		summary.outcomeMap[hostReport.Phase] = make(map[string]HostReport)
+	}
+	summary.outcomeMap[hostReport.Phase][hostReport.Host] = hostReport
+}
+
+// Description: returns the report of the run
+//
+// Parameters:
+// - workflowName: the name of the workflow
+// - err: the error returned by the run (nil on success)
+func (summary *runSummary) getReport(workflowName string, err error) *RunReport {
+	summary.mu.Lock()
+	defer summary.mu.Unlock()
+
+	endTime := time.Now()
+	report := &RunReport{
+		WorkflowName: workflowName,
+		StartTime:    summary.startTime,
+		EndTime:      endTime,
+		Duration:     endTime.Sub(summary.startTime),
+		Status:       getStatus(err),
+		Hosts:        []HostReport{},
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	if report.Status == "timeout" {
+		report.Status = "failed" // a timeout is a failure at the workflow level
+	}
+
+	for _, hostMap := range summary.outcomeMap {
+		for _, hostReport := range hostMap {
+			report.Hosts = append(report.Hosts, hostReport)
+		}
+	}
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		if report.Hosts[i].Phase != report.Hosts[j].Phase {
+			return report.Hosts[i].Phase < report.Hosts[j].Phase
+		}
+		return report.Hosts[i].Host < report.Hosts[j].Host
+	})
+
+	// count the hosts per outcome of each phase
+	report.Phases = []PhaseReport{}
+	for _, hostReport := range report.Hosts {
+		if len(report.Phases) == 0 || report.Phases[len(report.Phases)-1].Phase != hostReport.Phase {
+			report.Phases = append(report.Phases, PhaseReport{Phase: hostReport.Phase})
+		}
+		phaseReport := &report.Phases[len(report.Phases)-1]
+		switch hostReport.Status {
+		case "success", statusWouldChange:
+			phaseReport.Changed++
+		case statusUnchanged:
+			phaseReport.Unchanged++
+		case "failed", "timeout":
+			phaseReport.Failed++
+		default:
+			phaseReport.Skipped++
+		}
+	}
+	return report
+}
+
+// Description: returns a view of the number of hosts per outcome of each phase
+func (report *RunReport) GetRecapView() string {
+	var b strings.Builder
+	b.WriteString("Phase\tChanged\tUnchanged\tFailed\tSkipped\n")
+	for _, phaseReport := range report.Phases {
+		fmt.Fprintf(&b, "%s\t%d\t%d\t%d\t%d\n", phaseReport.Phase, phaseReport.Changed, phaseReport.Unchanged, phaseReport.Failed, phaseReport.Skipped)
+	}
+	return b.String()
+}
+
+// Description: returns a view of the report
+func (report *RunReport) GetView() string {
+	var b strings.Builder
+	b.WriteString("Phase\tHost\tAttempts\tDuration\tStatus\tRollback\n")
+	for _, hostReport := range report.Hosts {
+		rollback := hostReport.Rollback
+		if rollback == "" {
+			rollback = "none"
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%d\t%s\t%s\t%s\n", hostReport.Phase, hostReport.Host, hostReport.Attempt, hostReport.Duration.Round(time.Millisecond), hostReport.Status, rollback)
+	}
+	return b.String()
+}
+
+// Description: returns the report as JSON
+func (report *RunReport) ToJson() ([]byte, error) {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling report to json: %w", err)
+	}
+	return b, nil
+}
+
+// Description: represents the JUnit XML elements
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// Description: returns the report as JUnit XML
+//
+// Notes:
+// - a phase is a testsuite, a host is a testcase
+// - failed and timeout hosts are failures, cancelled, aborted, condition-skipped and no-check hosts are skipped
+func (report *RunReport) ToJunit() ([]byte, error) {
+	suites := junitTestSuites{
+		Name: report.WorkflowName,
+		Time: junitSeconds(report.Duration),
+	}
+
+	suiteIdx := make(map[string]int)
+	for _, hostReport := range report.Hosts {
+		idx, ok := suiteIdx[hostReport.Phase]
+		if !ok {
+			idx = len(suites.Suites)
+			suiteIdx[hostReport.Phase] = idx
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: hostReport.Phase, Timestamp: hostReport.StartTime.Format(time.RFC3339)})
+		}
+		suite := &suites.Suites[idx]
+
+		testCase := junitTestCase{
+			ClassName: report.WorkflowName + "." + hostReport.Phase,
+			Name:      hostReport.Host,
+			Time:      junitSeconds(hostReport.Duration),
+			SystemOut: fmt.Sprintf("attempts: %d, status: %s", hostReport.Attempt, hostReport.Status),
+		}
+		if hostReport.Rollback != "" {
+			testCase.SystemOut += strings.TrimSpace(fmt.Sprintf(", rollback: %s %s", hostReport.Rollback, hostReport.RollbackError))
+		}
+		switch hostReport.Status {
+		case "failed", "timeout":
+			testCase.Failure = &junitMessage{Message: hostReport.Status, Type: hostReport.Status, Text: hostReport.Error}
+			suite.Failures++
+			suites.Failures++
+		case "cancelled", "skipped (aborted)", statusSkippedCondition, statusSkippedNoCheck:
+			testCase.Skipped = &junitMessage{Message: hostReport.Status, Text: hostReport.Error}
+			suite.Skipped++
+			suites.Skipped++
+		}
+		suite.Tests++
+		suites.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for i := range suites.Suites {
+		var total time.Duration
+		for _, hostReport := range report.Hosts {
+			if hostReport.Phase == suites.Suites[i].Name {
+				total += hostReport.Duration
+			}
+		}
+		suites.Suites[i].Time = junitSeconds(total)
+	}
+
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling report to junit: %w", err)
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Description: returns a duration as JUnit seconds
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// Description: returns a copy of the report whose output values are redacted (the keys are kept)
+func (report *RunReport) redacted() *RunReport {
+	redacted := *report
+	redacted.Hosts = make([]HostReport, len(report.Hosts))
+	for i, hostReport := range report.Hosts {
+		hostReport.Outputs = redactOutputs(hostReport.Outputs)
+		redacted.Hosts[i] = hostReport
+	}
+	return &redacted
+}
+
+// Description: writes the report into a file
+//
+// Notes:
+// - the format is defined by the file extension: ".xml" for JUnit XML, JSON otherwise
+// - the file is readable by its owner only: the outputs may be secrets
+func (report *RunReport) WriteFile(path string) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		data, err = report.ToJunit()
+	} else {
+		data, err = report.ToJson()
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating report folder: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing report file %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package phase2
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Description: returns the report of a run whose phase "install" succeeds on h1, fails on h2, times out on h3 and is aborted on h4
+func getTestReport() *RunReport {
+	summary := newRunSummary(nil)
+	startTime := time.Now()
+	summary.record("install", "h1", startTime, 1, nil)
+	summary.record("install", "h2", startTime, 3, errors.New("apt failed"))
+	summary.record("install", "h3", startTime, 1, fmt.Errorf("host h3 > %w", ErrTimeout))
+	summary.recordSkipped("install", "h4", "skipped (aborted)")
+	summary.record("configure", "h1", startTime, 1, nil)
+	return summary.getReport("wkf", errors.New("errors occurred in tier 1"))
+}
+
+// Name: TestRunReportToJson
+func TestRunReportToJson(t *testing.T) {
+	report := getTestReport()
+	data, err := report.ToJson()
+	assert.NoError(t, err)
+
+	var got RunReport
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "wkf", got.WorkflowName)
+	assert.Equal(t, "failed", got.Status)
+	assert.Equal(t, "errors occurred in tier 1", got.Error)
+
+	// the hosts are sorted by phase then host
+	var keyList []string
+	for _, hostReport := range got.Hosts {
+		keyList = append(keyList, hostReport.Phase+"/"+hostReport.Host+"/"+hostReport.Status)
+	}
+	assert.Equal(t, []string{"configure/h1/success", "install/h1/success", "install/h2/failed", "install/h3/timeout", "install/h4/skipped (aborted)"}, keyList)
+	assert.Equal(t, 3, got.Hosts[2].Attempt)
+	assert.Equal(t, "apt failed", got.Hosts[2].Error)
+	assert.Equal(t, []PhaseReport{
+		{Phase: "configure", Changed: 1},
+		{Phase: "install", Changed: 1, Failed: 2, Skipped: 1},
+	}, got.Phases)
+}
+
+// Name: TestRunReportToJunit
+func TestRunReportToJunit(t *testing.T) {
+	report := getTestReport()
+	data, err := report.ToJunit()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), xml.Header))
+
+	var got junitTestSuites
+	assert.NoError(t, xml.Unmarshal(data, &got))
+	assert.Equal(t, "wkf", got.Name)
+	assert.Equal(t, 5, got.Tests)
+	assert.Equal(t, 2, got.Failures)
+	assert.Equal(t, 1, got.Skipped)
+
+	// a phase is a testsuite, a host is a testcase
+	if assert.Len(t, got.Suites, 2) {
+		suite := got.Suites[1]
+		assert.Equal(t, "install", suite.Name)
+		assert.Equal(t, 4, suite.Tests)
+		assert.Equal(t, 2, suite.Failures)
+		assert.Equal(t, 1, suite.Skipped)
+		if assert.Len(t, suite.Cases, 4) {
+			assert.Equal(t, "wkf.install", suite.Cases[0].ClassName)
+			assert.Equal(t, "h1", suite.Cases[0].Name)
+			assert.Nil(t, suite.Cases[0].Failure)
+			if assert.NotNil(t, suite.Cases[1].Failure) {
+				assert.Equal(t, "failed", suite.Cases[1].Failure.Type)
+				assert.Equal(t, "apt failed", suite.Cases[1].Failure.Text)
+			}
+			if assert.NotNil(t, suite.Cases[2].Failure) {
+				assert.Equal(t, "timeout", suite.Cases[2].Failure.Type)
+			}
+			if assert.NotNil(t, suite.Cases[3].Skipped) {
+				assert.Equal(t, "skipped (aborted)", suite.Cases[3].Skipped.Message)
+			}
+		}
+	}
+}
+
+// Name: TestRunReportWriteFile
+func TestRunReportWriteFile(t *testing.T) {
+	report := getTestReport()
+	dir := t.TempDir()
+
+	// Define test cases.
+	tests := []struct {
+		name       string // test case name
+		fileName   string // the input
+		wantPrefix string // expected start of the file
+	}{
+		{name: "Case 1: json", fileName: "report.json", wantPrefix: "{"},
+		{name: "Case 2: junit", fileName: "report.xml", wantPrefix: "<?xml"},
+		{name: "Case 3: junit upper case extension", fileName: "sub/report.XML", wantPrefix: "<?xml"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.fileName)
+			assert.NoError(t, report.WriteFile(path))
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(data), tt.wantPrefix))
+		})
+	}
+}
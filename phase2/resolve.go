@@ -26,9 +26,59 @@ func getHostList(phaseNode string, cfg *viperx.Viperx) ([]string, error) {
 	return nodes, nil
 }
 
-// Description: resolves phase parameters
+// Description: represents what a phase needs to run (resolved from the config and the registry)
+type phaseResolution struct {
+	hostList  []string
+	paramList [][]any
+	fn        fnEntry
+	check     fnEntry // zero value if the phase has no check function
+}
+
+// Description: resolves the hosts, the params and the functions of a phase
+//
+// Notes:
+// - used by a run and by a plan: both hit the same resolution errors
+// - the hosts are set when the error is about the params
+func (phase *Phase) resolve(env *runEnv, logger logx.Logger) (phaseResolution, error) {
+	var resolution phaseResolution
+
+	// 1 - get host
+	hostList, err := getHostList(phase.Node, env.cfg)
+	if err != nil {
+		return resolution, err
+	}
+	resolution.hostList = hostList
+
+	// 2 - get parameter
+	resolution.paramList, err = getParamList(phase.Param, env.cfg, env.summary.getOutputStore(), phase.item, logger)
+	if err != nil {
+		return resolution, err
+	}
+
+	// 3 - get PhaseFn
+	resolution.fn, err = getPhaseFn(phase.WkfName, phase.FnAlias, env.fnRegistry)
+	if err != nil {
+		return resolution, err
+	}
 
-func getParamList(phaseParam []string, cfg *viperx.Viperx, logger logx.Logger) ([][]any, error) {
+	// 31 - get the check function (optional)
+	if phase.Check != "" {
+		resolution.check, err = getPhaseFn(phase.WkfName, phase.Check, env.fnRegistry)
+		if err != nil {
+			return resolution, fmt.Errorf("check > %w", err)
+		}
+	}
+	return resolution, nil
+}
+
+// Description: resolves phase parameters
+//
+// Notes:
+// - a param is a config key or a reference to the output of an upstream phase (outputs.<phase>.<host>.<key>)
+// - a missing output is an error that wraps ErrOutputNotFound
+// - the param "item" of a phase expanded from a forEach phase is the item
+// - outputs is nil in plan mode: a reference to an output is resolved as a placeholder (<outputs.phase.host.key>)
+func getParamList(phaseParam []string, cfg *viperx.Viperx, outputs *OutputStore, item *forEachItem, logger logx.Logger) ([][]any, error) {
 	// check parameters
 	if cfg == nil {
 		return nil, fmt.Errorf("cfg is nil")
@@ -40,31 +90,62 @@ func getParamList(phaseParam []string, cfg *viperx.Viperx, logger logx.Logger) (
 	// resolve
 	resolved := make([][]any, len(phaseParam))
 	for i, key := range phaseParam {
+		// item of a forEach phase
+		if item != nil && key == forEachParam {
+			resolved[i] = toParam(item.value)
+			continue
+		}
+
+		// output of an upstream phase
+		if strings.HasPrefix(key, outputParamPrefix) {
+			if outputs == nil {
+				resolved[i] = []any{"<" + key + ">"}
+				continue
+			}
+			val, err := outputs.resolve(key)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = toParam(val)
+			continue
+		}
+
+		// config key
 		val := cfg.Get(key)
 		if val == nil {
 			logger.Warnf("param %q not found", key)
 			resolved[i] = []any{""}
 			continue
 		}
-
-		switch v := val.(type) {
-		case string:
-			resolved[i] = []any{v}
-		case []any:
-			anySlice := make([]any, len(v))
-			copy(anySlice, v)
-			resolved[i] = anySlice
-		case map[string]any:
-			b, _ := json.Marshal(v)
-			resolved[i] = []any{string(b)}
-		default:
-			resolved[i] = []any{fmt.Sprint(v)}
-		}
+		resolved[i] = toParam(val)
 	}
 
 	return resolved, nil
 }
 
+// Description: converts a config value or an output into a param
+func toParam(val any) []any {
+	switch v := val.(type) {
+	case string:
+		return []any{v}
+	case []any:
+		anySlice := make([]any, len(v))
+		copy(anySlice, v)
+		return anySlice
+	case []string:
+		anySlice := make([]any, len(v))
+		for i, item := range v {
+			anySlice[i] = item
+		}
+		return anySlice
+	case map[string]any:
+		b, _ := json.Marshal(v)
+		return []any{string(b)}
+	default:
+		return []any{fmt.Sprint(v)}
+	}
+}
+
 func getParamList1(phaseParam []string, cfg *viperx.Viperx, logger logx.Logger) ([]string, error) {
 	if cfg == nil || len(phaseParam) == 0 {
 		return nil, fmt.Errorf("looking up > param > %q > cfg or phaseParam is empty", phaseParam)
@@ -98,21 +179,21 @@ func getParamList1(phaseParam []string, cfg *viperx.Viperx, logger logx.Logger)
 	return resolved, nil
 }
 
-func getPhaseFn(workflowName, fnAlias string, fnRegistry *FnRegistry) (PhaseFn, error) {
+func getPhaseFn(workflowName, fnAlias string, fnRegistry *FnRegistry) (fnEntry, error) {
 	if fnAlias == "" {
-		return nil, fmt.Errorf("fn alias is empty")
+		return fnEntry{}, fmt.Errorf("fn alias is empty")
 	}
 
-	fn, ok := fnRegistry.Get(workflowName, fnAlias)
+	entry, ok := fnRegistry.lookup(workflowName, fnAlias)
 	if !ok {
-		return nil, fmt.Errorf("getting registred function for alias %s:%s (not registered)", workflowName, fnAlias)
+		return fnEntry{}, fmt.Errorf("getting registred function for alias %s:%s (not registered)", workflowName, fnAlias)
 	}
 
-	return fn, nil
+	return entry, nil
 }
 
-// Description: returns package import path + function name for a PhaseFn
-func describeFn(phaseFn PhaseFn, logger logx.Logger) (pkg string, name string) {
+// Description: returns package import path + function name for a PhaseFn or a PhaseFnCtx
+func describeFn(phaseFn any, logger logx.Logger) (pkg string, name string) {
 	if phaseFn == nil {
 		logger.Warnf("describeFn: nil function")
 		return "?", "?"
@@ -0,0 +1,156 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Description: represents the retry policy of a phase - applied per host
+//
+// Notes:
+// - Attempts is the total number of attempts (0 or 1 means no retry)
+// - the delay between 2 attempts starts at Delay, is multiplied by Factor after each attempt and is capped by MaxDelay
+// - if TransientOnly is true, only errors classified as transient are retried (see IsTransient)
+//
+// Example (workflow YAML):
+//
+//	retry:
+//	  attempts: 5
+//	  delay: 2s
+//	  maxDelay: 30s
+//	  factor: 2
+//	  transientOnly: true
+type RetryPolicy struct {
+	Attempts      int           `yaml:"attempts"`
+	Delay         time.Duration `yaml:"delay,omitempty"`
+	MaxDelay      time.Duration `yaml:"maxDelay,omitempty"`
+	Factor        float64       `yaml:"factor,omitempty"`
+	TransientOnly bool          `yaml:"transientOnly,omitempty"`
+}
+
+// Description: denotes an error that is worth retrying (eg. SSH not ready, apt lock held)
+//
+// Notes:
+// - a PhaseFn can return MarkTransient(err) to classify an error explicitly
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// Description: wraps an error to classify it as transient
+func MarkTransient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientError{Err: err}
+}
+
+// Description: list of error messages (lower case) classified as transient
+//
+// Notes:
+// - used when the error is not explicitly marked as transient
+var transientPatternList = []string{
+	"connection refused",
+	"connection reset",
+	"connection timed out",
+	"no route to host",
+	"broken pipe",
+	"i/o timeout",
+	"not ssh reachable",
+	"kex_exchange_identification",
+	"could not get lock",
+	"unable to acquire the dpkg frontend lock",
+	"temporary failure",
+	"try again",
+}
+
+// Description: reports whether an error is worth retrying
+//
+// Notes:
+// - an error is transient if it wraps a TransientError, ErrTimeout or if its message matches a known transient pattern
+// - a cancelled context is never transient
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, ErrCancelled) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var transientErr *TransientError
+	if errors.As(err, &transientErr) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range transientPatternList {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Description: returns the number of attempts defined by the policy (at least 1)
+func (policy *RetryPolicy) nbAttempt() int {
+	if policy == nil || policy.Attempts < 1 {
+		return 1
+	}
+	return policy.Attempts
+}
+
+// Description: reports whether an error should be retried according to the policy
+func (policy *RetryPolicy) shouldRetry(err error) bool {
+	if policy == nil || err == nil || errors.Is(err, ErrCancelled) {
+		return false
+	}
+	if policy.TransientOnly {
+		return IsTransient(err)
+	}
+	return true
+}
+
+// Description: returns the delay to wait before the next attempt
+//
+// Parameters:
+// - attempt: the attempt that just failed (1 = first attempt)
+//
+// Notes:
+// - the delay is capped by MaxDelay, including the first one (Delay > MaxDelay)
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	if policy == nil || policy.Delay <= 0 {
+		return 0
+	}
+	factor := policy.Factor
+	if factor < 1 {
+		factor = 1
+	}
+	delay := float64(policy.Delay)
+	for i := 1; i < attempt; i++ {
+		delay *= factor
+		if policy.MaxDelay > 0 && delay >= float64(policy.MaxDelay) {
+			break
+		}
+	}
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		return policy.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// Description: waits for the given delay or until the context is done
+//
+// Return:
+// - an error if the context is done before the delay expires
+func sleepCtx(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestBackoff
+func TestBackoff(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name    string        // test case name
+		policy  *RetryPolicy  // the input
+		attempt int           // the input
+		want    time.Duration // expected delay
+	}{
+		{name: "Case 1: no policy", policy: nil, attempt: 1, want: 0},
+		{name: "Case 2: no delay", policy: &RetryPolicy{Attempts: 3}, attempt: 2, want: 0},
+		{name: "Case 3: constant delay", policy: &RetryPolicy{Delay: time.Second}, attempt: 3, want: time.Second},
+		{name: "Case 4: factor below 1 is ignored", policy: &RetryPolicy{Delay: time.Second, Factor: 0.5}, attempt: 3, want: time.Second},
+		{name: "Case 5: first attempt", policy: &RetryPolicy{Delay: time.Second, Factor: 2}, attempt: 1, want: time.Second},
+		{name: "Case 6: exponential", policy: &RetryPolicy{Delay: time.Second, Factor: 2}, attempt: 4, want: 8 * time.Second},
+		{name: "Case 7: capped", policy: &RetryPolicy{Delay: time.Second, Factor: 2, MaxDelay: 5 * time.Second}, attempt: 4, want: 5 * time.Second},
+		{name: "Case 8: below the cap", policy: &RetryPolicy{Delay: time.Second, Factor: 2, MaxDelay: 5 * time.Second}, attempt: 3, want: 4 * time.Second},
+		{name: "Case 9: first attempt capped", policy: &RetryPolicy{Delay: 10 * time.Second, MaxDelay: 5 * time.Second}, attempt: 1, want: 5 * time.Second},
+		{name: "Case 10: delay above the cap", policy: &RetryPolicy{Delay: 10 * time.Second, Factor: 2, MaxDelay: 5 * time.Second}, attempt: 3, want: 5 * time.Second},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.backoff(tt.attempt))
+		})
+	}
+}
+
+// Name: TestShouldRetry
+func TestShouldRetry(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name   string       // test case name
+		policy *RetryPolicy // the input
+		err    error        // the input
+		want   bool         // expected result
+	}{
+		{name: "Case 1: no policy", policy: nil, err: errors.New("boom"), want: false},
+		{name: "Case 2: no error", policy: &RetryPolicy{Attempts: 3}, err: nil, want: false},
+		{name: "Case 3: any error", policy: &RetryPolicy{Attempts: 3}, err: errors.New("boom"), want: true},
+		{name: "Case 4: cancelled", policy: &RetryPolicy{Attempts: 3}, err: fmt.Errorf("phase: %w", ErrCancelled), want: false},
+		{name: "Case 5: transient only - permanent error", policy: &RetryPolicy{Attempts: 3, TransientOnly: true}, err: errors.New("boom"), want: false},
+		{name: "Case 6: transient only - transient error", policy: &RetryPolicy{Attempts: 3, TransientOnly: true}, err: errors.New("dial tcp: connection refused"), want: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.shouldRetry(tt.err))
+		})
+	}
+}
+
+// Name: TestIsTransient
+func TestIsTransient(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name string // test case name
+		err  error  // the input
+		want bool   // expected result
+	}{
+		{name: "Case 1: no error", err: nil, want: false},
+		{name: "Case 2: permanent error", err: errors.New("package not found"), want: false},
+		{name: "Case 3: marked transient", err: MarkTransient(errors.New("package not found")), want: true},
+		{name: "Case 4: wrapped marked transient", err: fmt.Errorf("install: %w", MarkTransient(errors.New("boom"))), want: true},
+		{name: "Case 5: timeout", err: fmt.Errorf("phase: %w", ErrTimeout), want: true},
+		{name: "Case 6: known pattern", err: errors.New("E: Could not get lock /var/lib/dpkg/lock-frontend"), want: true},
+		{name: "Case 7: cancelled", err: fmt.Errorf("phase: %w", ErrCancelled), want: false},
+		{name: "Case 8: context cancelled", err: context.Canceled, want: false},
+		{name: "Case 9: cancelled wins over transient", err: MarkTransient(context.Canceled), want: false},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransient(tt.err))
+		})
+	}
+}
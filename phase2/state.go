@@ -0,0 +1,245 @@
+package phase2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abtransitionit/gocore/viperx"
+)
+
+// Description: represents the persisted status of a phase on a host
+type HostState struct {
+	Status    string         `json:"status"` // success, unchanged, failed, timeout, cancelled, rolled back
+	Attempt   int            `json:"attempt"`
+	Error     string         `json:"error,omitempty"`
+	Outputs   map[string]any `json:"outputs,omitempty"` // the outputs published by the phase for the host
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Description: represents the persisted state of a workflow run (ie. a checkpoint)
+//
+// Notes:
+// - the state is keyed by the workflow name and the hash of the config
+// - a resumed run skips the phase/host pairs whose status is success
+// - the state file is readable by its owner only: the outputs may be secrets (their values are persisted only with ExecOption.PersistOutputs)
+type RunState struct {
+	WorkflowName string                          `json:"workflowName"`
+	ConfigHash   string                          `json:"configHash"`
+	UpdatedAt    time.Time                       `json:"updatedAt"`
+	Phases       map[string]map[string]HostState `json:"phases"` // phase > host > state
+
+	mu   sync.Mutex
+	path string
+}
+
+// Description: returns the default folder that contains the state files
+//
+// Notes:
+// - $GOLUC_STATE if set else ~/wkspc/.config/goluc/state
+func GetDefaultStateDir() (string, error) {
+	if dir := os.Getenv("GOLUC_STATE"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "wkspc", ".config", "goluc", "state"), nil
+}
+
+// Description: returns the hash of a config (hex encoded sha256 of its JSON representation)
+func getConfigHash(cfg *viperx.Viperx) (string, error) {
+	if cfg == nil {
+		return "", fmt.Errorf("cfg is nil")
+	}
+	b, err := json.Marshal(cfg.AllSettings()) // map keys are sorted: the hash is stable
+	if err != nil {
+		return "", fmt.Errorf("marshalling config: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Description: returns the path of the state file of a workflow for a config
+//
+// Parameters:
+// - cfg: the config used to run the workflow
+// - stateDir: the folder that contains the state files (default folder if empty)
+func (wkf *Workflow) GetStatePath(cfg *viperx.Viperx, stateDir string) (string, error) {
+	if stateDir == "" {
+		dir, err := GetDefaultStateDir()
+		if err != nil {
+			return "", err
+		}
+		stateDir = dir
+	}
+	cfgHash, err := getConfigHash(cfg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, fmt.Sprintf("%s-%s.json", wkf.Name, cfgHash[:12])), nil
+}
+
+// Description: loads the state of a workflow run for a config
+//
+// Notes:
+// - returns an empty state if no state file exists yet
+func (wkf *Workflow) LoadRunState(cfg *viperx.Viperx, stateDir string) (*RunState, error) {
+	path, err := wkf.GetStatePath(cfg, stateDir)
+	if err != nil {
+		return nil, err
+	}
+	cfgHash, err := getConfigHash(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &RunState{
+		WorkflowName: wkf.Name,
+		ConfigHash:   cfgHash,
+		Phases:       make(map[string]map[string]HostState),
+		path:         path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file %q: %w", path, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unmarshalling state file %q: %w", path, err)
+	}
+	if state.Phases == nil {
+		state.Phases = make(map[string]map[string]HostState)
+	}
+	return state, nil
+}
+
+// Description: resets the state of a workflow run for a config
+//
+// Parameters:
+// - phaseList: the phases to reset (all phases if empty)
+//
+// Notes:
+// - the state file is deleted when all phases are reset
+func (wkf *Workflow) ResetRunState(cfg *viperx.Viperx, stateDir string, phaseList ...string) error {
+	state, err := wkf.LoadRunState(cfg, stateDir)
+	if err != nil {
+		return err
+	}
+	if len(phaseList) == 0 {
+		if err := os.Remove(state.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("deleting state file %q: %w", state.path, err)
+		}
+		return nil
+	}
+	state.mu.Lock()
+	for _, phaseName := range phaseList {
+		delete(state.Phases, phaseName)
+	}
+	state.mu.Unlock()
+	return state.save()
+}
+
+// Description: returns the path of the state file
+func (state *RunState) GetPath() string {
+	return state.path
+}
+
+// Description: reports whether a phase already succeeded on a host
+//
+// Notes:
+// - a host whose outputs were redacted is not successful: it runs again to publish them again
+func (state *RunState) isSuccess(phaseName, hostName string) bool {
+	if state == nil {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	hostState := state.Phases[phaseName][hostName]
+	return isSuccessStatus(hostState.Status) && !isRedacted(hostState.Outputs)
+}
+
+// Description: records the status of a phase on a host and persists the state
+func (state *RunState) set(phaseName, hostName string, hostState HostState) error {
+	if state == nil {
+		return nil
+	}
+	state.mu.Lock()
+	if state.Phases[phaseName] == nil {
+		state.Phases[phaseName] = make(map[string]HostState)
+	}
+	state.Phases[phaseName][hostName] = hostState
+	state.mu.Unlock()
+	return state.save()
+}
+
+// Description: writes the state into its file
+//
+// Notes:
+// - the file is written into a temporary file then renamed: a crash never leaves a truncated state file
+func (state *RunState) save() error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(state.path), 0o700); err != nil {
+		return fmt.Errorf("creating state folder: %w", err)
+	}
+	tmpPath := state.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("writing state file %q: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, state.path); err != nil {
+		return fmt.Errorf("renaming state file %q: %w", tmpPath, err)
+	}
+	return nil
+}
+
+// Description: returns a view of the state
+func (state *RunState) GetView() string {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("Phase\tHost\tStatus\tAttempts\tUpdated\tError\n")
+
+	phaseList := make([]string, 0, len(state.Phases))
+	for phaseName := range state.Phases {
+		phaseList = append(phaseList, phaseName)
+	}
+	sort.Strings(phaseList)
+
+	for _, phaseName := range phaseList {
+		hostMap := state.Phases[phaseName]
+		hostList := make([]string, 0, len(hostMap))
+		for hostName := range hostMap {
+			hostList = append(hostList, hostName)
+		}
+		sort.Strings(hostList)
+		for _, hostName := range hostList {
+			hostState := hostMap[hostName]
+			errMsg := strings.ReplaceAll(hostState.Error, "\n", " ")
+			if errMsg == "" {
+				errMsg = "none"
+			}
+			fmt.Fprintf(&b, "%s\t%s\t%s\t%d\t%s\t%s\n", phaseName, hostName, hostState.Status, hostState.Attempt, hostState.UpdatedAt.Format(time.DateTime), errMsg)
+		}
+	}
+	return b.String()
+}
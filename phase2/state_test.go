@@ -0,0 +1,159 @@
+package phase2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestGetStatePath
+func TestGetStatePath(t *testing.T) {
+	// create inputs for the test : a workflow and a config
+	wf := &Workflow{Name: "wkf"}
+	newCfg := func(version string) *viperx.Viperx {
+		v := viper.New()
+		v.Set("nodes", []string{"h1", "h2"})
+		v.Set("version", version)
+		return &viperx.Viperx{Viper: v}
+	}
+	dir := t.TempDir()
+	refPath, err := wf.GetStatePath(newCfg("1.2"), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(refPath))
+	assert.True(t, strings.HasPrefix(filepath.Base(refPath), "wkf-"))
+
+	// Define test cases.
+	tests := []struct {
+		name     string         // test case name
+		wkfName  string         // the input
+		cfg      *viperx.Viperx // the input
+		wantSame bool           // expected: same state file as the reference
+	}{
+		{name: "Case 1: same workflow and config", wkfName: "wkf", cfg: newCfg("1.2"), wantSame: true},
+		{name: "Case 2: other config", wkfName: "wkf", cfg: newCfg("1.3"), wantSame: false},
+		{name: "Case 3: other workflow", wkfName: "other", cfg: newCfg("1.2"), wantSame: false},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := (&Workflow{Name: tt.wkfName}).GetStatePath(tt.cfg, dir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSame, path == refPath)
+		})
+	}
+
+	// a nil config has no hash
+	_, err = wf.GetStatePath(nil, dir)
+	assert.Error(t, err)
+}
+
+// Name: TestRunState
+func TestRunState(t *testing.T) {
+	// create inputs for the test : a workflow, a config and an empty state folder
+	wf := &Workflow{Name: "wkf"}
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	cfg := &viperx.Viperx{Viper: v}
+	dir := filepath.Join(t.TempDir(), "state")
+
+	// no state file yet: the state is empty
+	state, err := wf.LoadRunState(cfg, dir)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Phases)
+	assert.False(t, state.isSuccess("a", "h1"))
+
+	// the status of each phase/host is persisted
+	assert.NoError(t, state.set("a", "h1", HostState{Status: "success", Attempt: 2}))
+	assert.NoError(t, state.set("a", "h2", HostState{Status: "unchanged", Attempt: 1}))
+	assert.NoError(t, state.set("b", "h1", HostState{Status: "failed", Attempt: 3, Error: "boom"}))
+	info, err := os.Stat(state.GetPath())
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	// the state is loaded back
+	loaded, err := wf.LoadRunState(cfg, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "wkf", loaded.WorkflowName)
+	assert.Equal(t, state.ConfigHash, loaded.ConfigHash)
+	assert.Equal(t, 2, loaded.Phases["a"]["h1"].Attempt)
+	assert.Equal(t, "boom", loaded.Phases["b"]["h1"].Error)
+	assert.True(t, loaded.isSuccess("a", "h1"))
+	assert.True(t, loaded.isSuccess("a", "h2"))
+	assert.False(t, loaded.isSuccess("b", "h1"))
+
+	// a phase is reset
+	assert.NoError(t, wf.ResetRunState(cfg, dir, "a"))
+	loaded, err = wf.LoadRunState(cfg, dir)
+	assert.NoError(t, err)
+	assert.NotContains(t, loaded.Phases, "a")
+	assert.Contains(t, loaded.Phases, "b")
+
+	// all the phases are reset: the state file is deleted
+	assert.NoError(t, wf.ResetRunState(cfg, dir))
+	_, err = os.Stat(state.GetPath())
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+// Name: TestResume
+func TestResume(t *testing.T) {
+	// create inputs for the test : a workflow whose phase fails on h2 during the first run
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1", "h2"})
+	cfg := &viperx.Viperx{Viper: v}
+	var mu sync.Mutex
+	var runList []string // the phase/host pairs run
+	failH2 := true
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		runList = append(runList, phaseName+"/"+target)
+		if phaseName == "b" && target == "h2" && failH2 {
+			return false, errors.New("boom")
+		}
+		return true, nil
+	}))
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a": {FnAlias: "work", Node: "nodes"},
+		"b": {FnAlias: "work", Node: "nodes", Dependency: []string{"a"}},
+	}}
+	opt := ExecOption{Checkpoint: true, StateDir: t.TempDir()}
+
+	// the first run fails on h2
+	_, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", opt, logger)
+	assert.Error(t, err)
+	assert.ElementsMatch(t, []string{"a/h1", "a/h2", "b/h1", "b/h2"}, runList)
+
+	// the resumed run executes the failed phase/host pair only
+	runList, failH2 = nil, false
+	opt.Resume = true
+	report, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", opt, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b/h2"}, runList)
+	if assert.NotNil(t, report) {
+		statusMap := map[string]string{}
+		for _, hostReport := range report.Hosts {
+			statusMap[hostReport.Phase+"/"+hostReport.Host] = hostReport.Status
+		}
+		assert.Equal(t, map[string]string{"a/h1": "success (resumed)", "a/h2": "success (resumed)", "b/h1": "success (resumed)", "b/h2": "success"}, statusMap)
+	}
+
+	// a checkpointed run without resume starts from scratch
+	runList = nil
+	opt.Resume = false
+	_, err = wf.ExecuteWithOption(context.Background(), cfg, registry, "", opt, logger)
+	assert.NoError(t, err)
+	assert.Len(t, runList, 4)
+}
@@ -2,26 +2,149 @@ package phase2
 
 import (
 	"fmt"
+	"path"
+	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 )
 
-// Description: adds a function to the registry - the key is "Phase:FnAlias"
-func (registry *FnRegistry) Add(workflowName string, FnAlias string, phaseFn PhaseFn) {
-	key := fmt.Sprintf("%s:%s", workflowName, FnAlias)
-	registry.functionMap[key] = phaseFn
+// Description: adds a function to the registry - the key is "Workflow:FnAlias"
+//
+// Return:
+// - an error that wraps ErrFnDuplicate if the alias is already registered for the workflow
+//
+// Notes:
+// - the legacy function is adapted to a PhaseFnCtx (see AdaptPhaseFn)
+func (registry *FnRegistry) Add(workflowName string, FnAlias string, phaseFn PhaseFn) error {
+	return registry.set(workflowName, FnAlias, fnEntry{fn: AdaptPhaseFn(phaseFn), origin: phaseFn})
 }
 
-// Description: returns the go function with the given "Phase:FnAlias"
+// Description: adds a context-aware function to the registry - the key is "Workflow:FnAlias"
+//
+// Return:
+// - an error that wraps ErrFnDuplicate if the alias is already registered for the workflow
+func (registry *FnRegistry) AddCtx(workflowName string, FnAlias string, phaseFn PhaseFnCtx) error {
+	return registry.set(workflowName, FnAlias, fnEntry{fn: phaseFn, origin: phaseFn})
+}
+
+// Description: registers a function once under a name - to be aliased into workflows (see Alias)
+//
+// Return:
+// - an error that wraps ErrFnDuplicate if the name is already registered
+//
+// Notes:
+// - the legacy function is adapted to a PhaseFnCtx (see AdaptPhaseFn)
+func (registry *FnRegistry) Register(name string, phaseFn PhaseFn) error {
+	return registry.setName(name, fnEntry{fn: AdaptPhaseFn(phaseFn), origin: phaseFn, name: name})
+}
+
+// Description: registers a context-aware function once under a name - to be aliased into workflows (see Alias)
+//
+// Return:
+// - an error that wraps ErrFnDuplicate if the name is already registered
+func (registry *FnRegistry) RegisterCtx(name string, phaseFn PhaseFnCtx) error {
+	return registry.setName(name, fnEntry{fn: phaseFn, origin: phaseFn, name: name})
+}
+
+// Description: makes a function registered once (see Register) available to a workflow under an alias
+//
+// Parameters:
+// - workflowName: the workflow that uses the function
+// - fnAlias: the alias used by the phases of the workflow (the fn: key)
+// - name: the name under which the function was registered
+//
+// Example Usage:
+//
+//	registry := phase2.NewFnRegistry()
+//	registry.Register("node.CheckSshConf", node.CheckSshConf)
+//	registry.Alias("kind", "checkSsh", "node.CheckSshConf")
+//	registry.Alias("kbe", "checkSsh", "node.CheckSshConf")
+func (registry *FnRegistry) Alias(workflowName, fnAlias, name string) error {
+	registry.mu.RLock()
+	entry, ok := registry.nameMap[name]
+	registry.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("aliasing %s:%s > function %q is not registered", workflowName, fnAlias, name)
+	}
+	return registry.set(workflowName, fnAlias, entry)
+}
+
+// Description: stores an entry under "Workflow:FnAlias"
+func (registry *FnRegistry) set(workflowName, fnAlias string, entry fnEntry) error {
+	if workflowName == "" || fnAlias == "" {
+		return fmt.Errorf("registering %s:%s > workflow name and fn alias must not be empty", workflowName, fnAlias)
+	}
+	key := fmt.Sprintf("%s:%s", workflowName, fnAlias)
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.functionMap[key]; ok {
+		return fmt.Errorf("registering %s > %w", key, ErrFnDuplicate)
+	}
+	registry.functionMap[key] = entry
+	return nil
+}
+
+// Description: stores an entry under its name
+func (registry *FnRegistry) setName(name string, entry fnEntry) error {
+	if name == "" {
+		return fmt.Errorf("registering function > name must not be empty")
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.nameMap[name]; ok {
+		return fmt.Errorf("registering function %q > %w", name, ErrFnDuplicate)
+	}
+	registry.nameMap[name] = entry
+	return nil
+}
+
+// Description: returns the go function with the given "Workflow:FnAlias"
+//
+// Notes:
+// - returns the legacy function as registered (see Add)
+// - a context-aware function (see AddCtx) is adapted to a PhaseFn that runs it with context.Background()
+// - use GetCtx to get the function the engine runs
 func (registry *FnRegistry) Get(workflowName, fnAlias string) (PhaseFn, bool) {
+	entry, ok := registry.lookup(workflowName, fnAlias)
+	if !ok {
+		return nil, false
+	}
+	if phaseFn, isLegacy := entry.origin.(PhaseFn); isLegacy {
+		return phaseFn, true
+	}
+	return adaptPhaseFnCtx(entry.fn), true
+}
+
+// Description: returns the context-aware go function with the given "Workflow:FnAlias"
+//
+// Notes:
+// - a legacy function (see Add) is returned adapted (see AdaptPhaseFn)
+func (registry *FnRegistry) GetCtx(workflowName, fnAlias string) (PhaseFnCtx, bool) {
+	entry, ok := registry.lookup(workflowName, fnAlias)
+	return entry.fn, ok
+}
+
+// Description: returns the registry entry with the given "Workflow:FnAlias"
+func (registry *FnRegistry) lookup(workflowName, fnAlias string) (fnEntry, bool) {
+	if registry == nil {
+		return fnEntry{}, false
+	}
 	key := fmt.Sprintf("%s:%s", workflowName, fnAlias)
-	fn, ok := registry.functionMap[key]
-	return fn, ok
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	entry, ok := registry.functionMap[key]
+	return entry, ok
 }
 
-// Description: returns the "Phase:FnAlias" of all registered functions
+// Description: returns the FnAlias of all the functions registered for a workflow
 func (registry *FnRegistry) List(workflowName string) []string {
+	if registry == nil {
+		return nil
+	}
 	prefix := workflowName + ":"
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
 	nameList := make([]string, 0, len(registry.functionMap))
 	for k := range registry.functionMap {
 		if strings.HasPrefix(k, prefix) {
@@ -32,9 +155,67 @@ func (registry *FnRegistry) List(workflowName string) []string {
 	return nameList
 }
 
-// description: check a "Phase:FnAlias" is in the registry
+// description: check a "Workflow:FnAlias" is in the registry
 func (registry *FnRegistry) Has(workflowName, fnAlias string) bool {
-	key := fmt.Sprintf("%s:%s", workflowName, fnAlias)
-	_, ok := registry.functionMap[key]
+	_, ok := registry.lookup(workflowName, fnAlias)
 	return ok
 }
+
+// Description: returns the workflows that have at least one registered function
+func (registry *FnRegistry) ListWorkflow() []string {
+	if registry == nil {
+		return nil
+	}
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	workflowSet := make(map[string]bool)
+	for k := range registry.functionMap {
+		workflowSet[k[:strings.LastIndex(k, ":")]] = true
+	}
+	workflowList := make([]string, 0, len(workflowSet))
+	for workflowName := range workflowSet {
+		workflowList = append(workflowList, workflowName)
+	}
+	sort.Strings(workflowList)
+	return workflowList
+}
+
+// Description: describes a registered function
+type FnInfo struct {
+	Workflow string // the workflow that uses the function
+	Alias    string // the alias used by the phases (the fn: key)
+	Name     string // the name under which the function was registered once (empty if added directly)
+	Module   string // the package of the function (eg. mock/node)
+	Function string // the function (eg. node.CheckSshConf)
+	IsCtx    bool   // the function is a PhaseFnCtx (ie. honors cancellation)
+}
+
+// Description: returns the description of the functions registered for a workflow
+//
+// Notes:
+// - sorted by alias
+func (registry *FnRegistry) Describe(workflowName string) []FnInfo {
+	var infoList []FnInfo
+	for _, fnAlias := range registry.List(workflowName) {
+		entry, ok := registry.lookup(workflowName, fnAlias)
+		if !ok {
+			continue
+		}
+		module, fnName := "<??>", "<??>"
+		if rf := runtime.FuncForPC(reflect.ValueOf(entry.origin).Pointer()); rf != nil {
+			fullName := strings.TrimPrefix(rf.Name(), "github.com/abtransitionit/")
+			module = path.Dir(fullName)
+			fnName = path.Base(fullName)
+		}
+		_, isCtx := entry.origin.(PhaseFnCtx)
+		infoList = append(infoList, FnInfo{
+			Workflow: workflowName,
+			Alias:    fnAlias,
+			Name:     entry.name,
+			Module:   module,
+			Function: fnName,
+			IsCtx:    isCtx,
+		})
+	}
+	return infoList
+}
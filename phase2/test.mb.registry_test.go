@@ -0,0 +1,99 @@
+package phase2
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func registryTestFn(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+	return true, nil
+}
+
+// Name: TestFnRegistry
+func TestFnRegistry(t *testing.T) {
+	// registries are independent
+	registry := NewFnRegistry()
+	other := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf1", "check", registryTestFn))
+	assert.True(t, registry.Has("wkf1", "check"))
+	assert.False(t, other.Has("wkf1", "check"))
+
+	// duplicate registration
+	err := registry.Add("wkf1", "check", registryTestFn)
+	assert.ErrorIs(t, err, ErrFnDuplicate)
+
+	// register once, alias into several workflows
+	assert.NoError(t, registry.Register("node.check", registryTestFn))
+	assert.ErrorIs(t, registry.Register("node.check", registryTestFn), ErrFnDuplicate)
+	assert.NoError(t, registry.Alias("wkf2", "check", "node.check"))
+	assert.NoError(t, registry.Alias("wkf3", "checkNode", "node.check"))
+	assert.Error(t, registry.Alias("wkf3", "other", "node.unknown"))
+	assert.Equal(t, []string{"wkf1", "wkf2", "wkf3"}, registry.ListWorkflow())
+	assert.Equal(t, []string{"checkNode"}, registry.List("wkf3"))
+
+	// introspection
+	infoList := registry.Describe("wkf2")
+	if assert.Len(t, infoList, 1) {
+		assert.Equal(t, "check", infoList[0].Alias)
+		assert.Equal(t, "node.check", infoList[0].Name)
+		assert.Equal(t, "phase2.registryTestFn", infoList[0].Function)
+		assert.False(t, infoList[0].IsCtx)
+	}
+
+	// accessors: Get returns the legacy signature, GetCtx the context-aware one
+	logger := logx.GetLogger()
+	assert.NoError(t, registry.AddCtx("wkf4", "ctx", func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		return ctx != nil, nil
+	}))
+	for _, key := range [][2]string{{"wkf1", "check"}, {"wkf4", "ctx"}} {
+		workflowName, alias := key[0], key[1]
+		legacyFn, ok := registry.Get(workflowName, alias)
+		if assert.True(t, ok) {
+			changed, err := legacyFn("phase", "h1", nil, logger)
+			assert.NoError(t, err)
+			assert.True(t, changed)
+		}
+		ctxFn, ok := registry.GetCtx(workflowName, alias)
+		if assert.True(t, ok) {
+			changed, err := ctxFn(context.Background(), "phase", "h1", nil, logger)
+			assert.NoError(t, err)
+			assert.True(t, changed)
+		}
+	}
+	_, ok := registry.Get("wkf1", "unknown")
+	assert.False(t, ok)
+
+	// a nil registry has no function
+	var none *FnRegistry
+	assert.False(t, none.Has("wkf1", "check"))
+	assert.Empty(t, none.List("wkf1"))
+	assert.Empty(t, none.ListWorkflow())
+	assert.Empty(t, none.Describe("wkf1"))
+}
+
+// Name: TestFnRegistryConcurrent
+//
+// Notes:
+// - meaningful with go test -race
+func TestFnRegistryConcurrent(t *testing.T) {
+	registry := NewFnRegistry()
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			alias := fmt.Sprintf("fn%d", i)
+			assert.NoError(t, registry.Add("wkf", alias, registryTestFn))
+			_, ok := registry.Get("wkf", alias)
+			assert.True(t, ok)
+			registry.List("wkf")
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, registry.List("wkf"), 50)
+}
@@ -1,12 +1,52 @@
 package phase2
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/abtransitionit/gocore/logx"
 )
 
 // Description: represents the signature of a GO function to be executed on a target.
+//
+// Notes:
+// - legacy signature: the function cannot be interrupted once started
+// - on timeout or cancellation, the engine abandons it after killGracePeriod: the function and its child processes (eg. ssh) keep running until it returns
+// - use PhaseFnCtx for functions that must honor cancellation and deadlines
 type PhaseFn func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error)
 
+// Description: represents the signature of a context-aware GO function to be executed on a target.
+//
+// Notes:
+// - the ctx is cancelled on Ctrl+C (see ctx.NewPhaseCtx) or when the workflow is aborted
+// - the function should pass the ctx to any blocking call (CLI, SSH, HTTP, ...)
+type PhaseFnCtx func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error)
+
+// Description: adapts a legacy PhaseFn into a PhaseFnCtx
+//
+// Notes:
+// - the ctx is checked before the function starts: a cancelled ctx prevents the call
+// - once started, the legacy function runs until it returns: it cannot be killed (see PhaseFn)
+func AdaptPhaseFn(phaseFn PhaseFn) PhaseFnCtx {
+	return func(ctx context.Context, phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return phaseFn(phaseName, target, params, logger)
+	}
+}
+
+// Description: adapts a PhaseFnCtx into a legacy PhaseFn
+//
+// Notes:
+// - the function runs with context.Background(): it cannot be cancelled
+func adaptPhaseFnCtx(phaseFn PhaseFnCtx) PhaseFn {
+	return func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		return phaseFn(context.Background(), phaseName, target, params, logger)
+	}
+}
+
 // type PhaseFn func(target string, params []string, logger logx.Logger) (bool, error)
 // type PhaseFn func(ctx context.Context, params any, logger logx.Logger) error
 
@@ -14,21 +54,55 @@ type PhaseFn func(phaseName, target string, params [][]any, logger logx.Logger)
 type GoFunction struct {
 	PhaseName string
 	Name      string
-	Func      PhaseFn
+	Func      PhaseFnCtx
 	ParamList [][]any
+	Retry     *RetryPolicy
+	Timeout   time.Duration
+	Check     PhaseFnCtx // optional: reports whether the host is already in the desired state (the function is then not run)
+	checkOnly bool       // run the check function only (see ExecOption.CheckOnly)
+}
+
+// Description: represents a registered function
+//
+// Notes:
+// - fn is the function called by the engine
+// - origin is the function as registered (used to describe it: package, name)
+// - name is the name under which the function was registered once (see Register), empty otherwise
+type fnEntry struct {
+	fn     PhaseFnCtx
+	origin any
+	name   string
 }
 
 // Description: represents a map of function (that are registered and can be executed).
+//
+// Notes:
+// - safe for concurrent use
+// - functionMap is keyed by "Workflow:FnAlias", nameMap by the name of a function registered once (see Register)
 type FnRegistry struct {
-	functionMap map[string]PhaseFn
+	mu          sync.RWMutex
+	functionMap map[string]fnEntry
+	nameMap     map[string]fnEntry
 }
 
-// Description: define an instance of a registry as a singleton
-var globalRegistry = &FnRegistry{
-	functionMap: make(map[string]PhaseFn),
+// Description: constructor that returns a new and empty instance of FnRegistry
+//
+// Notes:
+// - use one registry per workflow set (or per test) to avoid sharing registrations
+func NewFnRegistry() *FnRegistry {
+	return &FnRegistry{
+		functionMap: make(map[string]fnEntry),
+		nameMap:     make(map[string]fnEntry),
+	}
 }
 
-// Description: constructor that returns an instance of FnRegistry
+// Description: define an instance of a registry as a singleton
+var globalRegistry = NewFnRegistry()
+
+// Description: returns the default (package-level) instance of FnRegistry
+//
+// Notes:
+// - shared by all the callers of the binary: prefer NewFnRegistry for independent registries
 func GetFnRegistry() *FnRegistry {
 	return globalRegistry
 }
@@ -5,27 +5,45 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/abtransitionit/gocore/logx"
-	"github.com/abtransitionit/gocore/mock/yamlx"
 )
 
 // Description: represents a set of phases.
 type Workflow struct {
-	Name        string           `yaml:"name"`
-	Description string           `yaml:"description"`
-	Phases      map[string]Phase `yaml:"phases"`
+	Name        string              `yaml:"name"`
+	Description string              `yaml:"description"`
+	Timeout     time.Duration       `yaml:"timeout,omitempty"` // default timeout of a host execution (for phases that do not define one)
+	Include     []Include           `yaml:"include,omitempty"` // other workflow files whose phases become part of this workflow
+	Phases      map[string]Phase    `yaml:"phases"`
+	source      []byte              // the YAML the workflow is loaded from (if any)
+	includeMap  map[string][]byte   // the YAML of the included files (path > content)
+	groupMap    map[string][]string // the phases an outer phase depends on when it depends on an included group (prefix > phases)
 }
 
 // Description: represents a phase
 type Phase struct {
-	WkfName     string   `yaml:"wkfName,omitempty"`
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	FnAlias     string   `yaml:"fn"`
-	Dependency  []string `yaml:"dependency,omitempty"`
-	Param       []string `yaml:"param,omitempty"`
-	Node        string   `yaml:"node,omitempty"`
+	WkfName      string        `yaml:"wkfName,omitempty"`
+	Name         string        `yaml:"name"`
+	Description  string        `yaml:"description"`
+	FnAlias      string        `yaml:"fn"`
+	Check        string        `yaml:"check,omitempty"` // fn alias of the function that reports whether a host is already in the desired state (the fn is then not run)
+	Undo         string        `yaml:"undo,omitempty"`  // fn alias of the compensating function run on rollback (see ExecOption.Rollback)
+	Dependency   []string      `yaml:"dependency,omitempty"`
+	Param        []string      `yaml:"param,omitempty"`
+	Node         string        `yaml:"node,omitempty"`
+	Tags         []string      `yaml:"tags,omitempty"`    // labels used to select phases (see PhaseFilter)
+	ForEach      string        `yaml:"forEach,omitempty"` // config key of a list: the phase is expanded into one phase per item (the param "item" is the item)
+	Retry        *RetryPolicy  `yaml:"retry,omitempty"`
+	Timeout      time.Duration `yaml:"timeout,omitempty"`      // bounds each host execution (0 means no timeout)
+	Serial       int           `yaml:"serial,omitempty"`       // number of hosts per batch - batches run one after another (0 means one batch)
+	MaxParallel  int           `yaml:"maxParallel,omitempty"`  // max number of hosts running at the same time (0 means no limit)
+	MaxFailRatio *float64      `yaml:"maxFailRatio,omitempty"` // abort the remaining batches when the failure ratio of a batch exceeds this value (0..1)
+	When         string        `yaml:"when,omitempty"`         // run the phase only if this expression is true for the config (eg. cni == cilium)
+	skipReason   string        // why the phase is not run (eg. its when condition is false)
+	group        string        // the include prefix of the phase (empty if the phase is not included)
+	item         *forEachItem  // the item of a phase expanded from a forEach phase (nil otherwise)
 }
 
 // Description: constructor that returns an instance of a Workflow
@@ -35,6 +53,10 @@ type Phase struct {
 // - fileName: The name of the YAML file containing the workflow definition.
 // - cmdPathName: The name of the directory containing the YAML file (relative to the worflow folder).
 // - logger: The logger to use for logging.
+//
+// Notes:
+// - the file is located relative to the source file of the caller: it requires the source tree at runtime
+// - use LoadWorkflow (embedded default + overrides) for a binary installed on another machine
 func GetWorkflow(fileName, cmdPathName string, logger logx.Logger) (*Workflow, error) {
 
 	// 1. Define the path of the workflow YAML
@@ -48,13 +70,7 @@ func GetWorkflow(fileName, cmdPathName string, logger logx.Logger) (*Workflow, e
 	logger.Debugf("found workflow file: %s", workflowFilePath)
 
 	// 2. Load the yaml file into a struct
-	workflow, err := yamlx.LoadYamlFile[Workflow](workflowFilePath)
-	if err != nil {
-		return nil, fmt.Errorf("loading workflow yaml file from %s: %w", workflowFilePath, err)
-	}
-
-	return workflow, nil
-
+	return LoadWorkflowFile(workflowFilePath)
 }
 
 func GetPhase(workflowName string) *Phase {
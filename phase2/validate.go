@@ -0,0 +1,295 @@
+package phase2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/abtransitionit/gocore/viperx"
+	"gopkg.in/yaml.v3"
+)
+
+// Description: represents a problem found in a workflow
+type ValidationProblem struct {
+	Phase   string // the phase concerned ("" for the workflow itself)
+	Kind    string // yaml, dependency, cycle, fn, node, param, when, option
+	Message string
+}
+
+// Description: represents all the problems found in a workflow
+//
+// Notes:
+// - returned by Workflow.Validate
+type ValidationError struct {
+	WorkflowName string
+	Problems     []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	msgList := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		if problem.Phase == "" {
+			msgList = append(msgList, fmt.Sprintf("%s: %s", problem.Kind, problem.Message))
+			continue
+		}
+		msgList = append(msgList, fmt.Sprintf("phase %s > %s: %s", problem.Phase, problem.Kind, problem.Message))
+	}
+	return fmt.Sprintf("workflow %q has %d problem(s):\n%s", e.WorkflowName, len(e.Problems), strings.Join(msgList, "\n"))
+}
+
+// Description: returns a view of the problems
+func (e *ValidationError) GetView() string {
+	var b strings.Builder
+	b.WriteString("Phase\tKind\tProblem\n")
+	for _, problem := range e.Problems {
+		phaseName := problem.Phase
+		if phaseName == "" {
+			phaseName = "-"
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", phaseName, problem.Kind, problem.Message)
+	}
+	return b.String()
+}
+
+func (e *ValidationError) add(phaseName, kind, format string, args ...any) {
+	e.Problems = append(e.Problems, ValidationProblem{Phase: phaseName, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// Description: checks a workflow against its config and its function registry
+//
+// Parameters:
+// - cfg: the workflow config
+// - fnRegistry: the GO functions used by the workflow
+//
+// Return:
+// - nil if the workflow is valid
+// - a *ValidationError that lists all the problems found otherwise
+//
+// Notes:
+// - checks: unknown YAML keys, unknown dependencies, cycles (with the cycle path), unresolved nodes and params, unregistered fn, check and undo aliases
+// - checks: a param that references an output (outputs.<phase>.<host>.<key>) is produced by an upstream phase
+// - unknown YAML keys are only detected for a workflow loaded from YAML
+func (wf *Workflow) Validate(cfg *viperx.Viperx, fnRegistry *FnRegistry) error {
+	verr := &ValidationError{WorkflowName: wf.Name}
+
+	// 1 - check the YAML syntax (unknown keys)
+	if wf.source != nil {
+		checkYamlKey(wf.source, "", verr)
+	}
+	includeList := make([]string, 0, len(wf.includeMap))
+	for includePath := range wf.includeMap {
+		includeList = append(includeList, includePath)
+	}
+	sort.Strings(includeList)
+	for _, includePath := range includeList {
+		checkYamlKey(wf.includeMap[includePath], includePath, verr)
+	}
+
+	// 2 - check the workflow options
+	if wf.Timeout < 0 {
+		verr.add("", "option", "negative timeout: %s", wf.Timeout)
+	}
+
+	// 3 - check each phase - in a deterministic order
+	nameList := make([]string, 0, len(wf.Phases))
+	for name := range wf.Phases {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+
+	for _, name := range nameList {
+		phase := wf.Phases[name]
+
+		// 31 - dependencies
+		for _, dep := range phase.Dependency {
+			if _, ok := wf.Phases[dep]; !ok {
+				verr.add(name, "dependency", "dependency %q does not exist", dep)
+			}
+		}
+
+		// 32 - fn
+		if phase.FnAlias == "" {
+			verr.add(name, "fn", "fn alias is empty")
+		} else if fnRegistry == nil || !fnRegistry.Has(wf.Name, phase.FnAlias) {
+			verr.add(name, "fn", "fn alias %s:%s is not registered", wf.Name, phase.FnAlias)
+		}
+
+		// 321 - check and undo
+		if phase.Check != "" && (fnRegistry == nil || !fnRegistry.Has(wf.Name, phase.Check)) {
+			verr.add(name, "fn", "check alias %s:%s is not registered", wf.Name, phase.Check)
+		}
+		if phase.Undo != "" && (fnRegistry == nil || !fnRegistry.Has(wf.Name, phase.Undo)) {
+			verr.add(name, "fn", "undo alias %s:%s is not registered", wf.Name, phase.Undo)
+		}
+
+		// 33 - node
+		if _, err := getHostList(phase.Node, cfg); err != nil {
+			verr.add(name, "node", "%v", err)
+		}
+
+		// 34 - param
+		for _, key := range phase.Param {
+			ref, isOutput, err := parseOutputRef(key, wf.Phases)
+			if isOutput {
+				wf.checkOutputRef(name, key, ref, err, cfg, verr)
+				continue
+			}
+			if phase.ForEach != "" && key == forEachParam {
+				continue
+			}
+			if cfg == nil || cfg.Get(key) == nil {
+				verr.add(name, "param", "param %q not found in config", key)
+			}
+		}
+
+		// 341 - forEach
+		if phase.ForEach != "" {
+			if _, err := getForEachItemList(phase.ForEach, cfg); err != nil {
+				verr.add(name, "param", "%v", err)
+			}
+		}
+
+		// 35 - when
+		if phase.When != "" {
+			if _, err := parseWhen(phase.When); err != nil {
+				verr.add(name, "when", "parsing %q: %v", phase.When, err)
+			}
+		}
+
+		// 36 - options
+		if phase.Timeout < 0 {
+			verr.add(name, "option", "negative timeout: %s", phase.Timeout)
+		}
+		if phase.Serial < 0 || phase.MaxParallel < 0 {
+			verr.add(name, "option", "serial and maxParallel must not be negative")
+		}
+		if phase.MaxFailRatio != nil && (*phase.MaxFailRatio < 0 || *phase.MaxFailRatio > 1) {
+			verr.add(name, "option", "maxFailRatio must be between 0 and 1: %v", *phase.MaxFailRatio)
+		}
+		if phase.Retry != nil && (phase.Retry.Attempts < 0 || phase.Retry.Delay < 0 || phase.Retry.MaxDelay < 0 || phase.Retry.Factor < 0) {
+			verr.add(name, "option", "retry settings must not be negative")
+		}
+	}
+
+	// 4 - check cycles
+	for _, cycle := range wf.findCycleList() {
+		verr.add("", "cycle", "circular dependency: %s", strings.Join(cycle, " -> "))
+	}
+
+	// handle success
+	if len(verr.Problems) == 0 {
+		return nil
+	}
+	return verr
+}
+
+// Description: reports the unknown keys of a workflow YAML
+func checkYamlKey(source []byte, fileName string, verr *ValidationError) {
+	decoder := yaml.NewDecoder(bytes.NewReader(source))
+	decoder.KnownFields(true)
+
+	var strict Workflow
+	err := decoder.Decode(&strict)
+	if err == nil {
+		return
+	}
+	prefix := ""
+	if fileName != "" {
+		prefix = fileName + ": "
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		for _, msg := range typeErr.Errors {
+			verr.add("", "yaml", "%s%s", prefix, msg)
+		}
+		return
+	}
+	verr.add("", "yaml", "%s%v", prefix, err)
+}
+
+// Description: returns the cycles of the dependency graph
+//
+// Return:
+// - a list of cycle paths (eg. [a b c a] for a -> b -> c -> a)
+//
+// Notes:
+// - depth-first search: a phase is on the stack while its dependencies are explored
+// - each cycle is reported once
+func (wf *Workflow) findCycleList() [][]string {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	stateMap := make(map[string]int, len(wf.Phases))
+	var stack []string
+	var cycleList [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		stateMap[name] = onStack
+		stack = append(stack, name)
+
+		depList := append([]string(nil), wf.Phases[name].Dependency...)
+		sort.Strings(depList)
+		for _, dep := range depList {
+			if _, ok := wf.Phases[dep]; !ok {
+				continue // reported as an unknown dependency
+			}
+			switch stateMap[dep] {
+			case unvisited:
+				visit(dep)
+			case onStack:
+				// the cycle is the part of the stack that starts at dep
+				idx := len(stack) - 1
+				for stack[idx] != dep {
+					idx--
+				}
+				cycle := append([]string(nil), stack[idx:]...)
+				cycle = append(cycle, dep)
+				cycleList = append(cycleList, cycle)
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		stateMap[name] = done
+	}
+
+	nameList := make([]string, 0, len(wf.Phases))
+	for name := range wf.Phases {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList) // deterministic
+	for _, name := range nameList {
+		if stateMap[name] == unvisited {
+			visit(name)
+		}
+	}
+	return cycleList
+}
+
+// Description: checks a param that references the output of a phase
+//
+// Notes:
+// - the producer must exist and be an upstream dependency (direct or not) of the consumer
+// - the host must be one of the hosts of the producer (when its node resolves)
+func (wf *Workflow) checkOutputRef(phaseName, param string, ref outputRef, err error, cfg *viperx.Viperx, verr *ValidationError) {
+	if err != nil {
+		verr.add(phaseName, "param", "%v", err)
+		return
+	}
+	producer, ok := wf.Phases[ref.phaseName]
+	if !ok {
+		verr.add(phaseName, "param", "param %q references phase %q that does not exist", param, ref.phaseName)
+		return
+	}
+	if !wf.getUpstreamSet(phaseName)[ref.phaseName] {
+		verr.add(phaseName, "param", "param %q references phase %q that is not an upstream dependency", param, ref.phaseName)
+	}
+	if hostList, err := getHostList(producer.Node, cfg); err == nil && !slices.Contains(hostList, ref.hostName) {
+		verr.add(phaseName, "param", "param %q references host %q that is not a host of phase %q %v", param, ref.hostName, ref.phaseName, hostList)
+	}
+}
@@ -0,0 +1,123 @@
+package phase2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestValidate
+func TestValidate(t *testing.T) {
+	// create inputs for the test : a config and a registry
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	v.Set("version", "1.2")
+	cfg := &viperx.Viperx{Viper: v}
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		return true, nil
+	}))
+	ratio := 2.0
+
+	// Define test cases.
+	tests := []struct {
+		name   string           // test case name
+		phases map[string]Phase // the input
+		want   []string         // expected problems (phase/kind)
+	}{
+		{name: "Case 1: valid workflow", phases: map[string]Phase{
+			"a": {FnAlias: "work", Node: "nodes", Param: []string{"version"}},
+			"b": {FnAlias: "work", Node: "nodes", Dependency: []string{"a"}, When: "version == 1.2"},
+		}},
+		{name: "Case 2: unknown dependency", phases: map[string]Phase{
+			"a": {FnAlias: "work", Node: "nodes", Dependency: []string{"missing"}},
+		}, want: []string{"a/dependency"}},
+		{name: "Case 3: unregistered functions", phases: map[string]Phase{
+			"a": {FnAlias: "missing", Node: "nodes", Check: "missing", Undo: "missing"},
+		}, want: []string{"a/fn", "a/fn", "a/fn"}},
+		{name: "Case 4: unresolved node and param", phases: map[string]Phase{
+			"a": {FnAlias: "work", Node: "missing", Param: []string{"missing"}},
+		}, want: []string{"a/node", "a/param"}},
+		{name: "Case 5: invalid when and options", phases: map[string]Phase{
+			"a": {FnAlias: "work", Node: "nodes", When: "version ==", MaxFailRatio: &ratio},
+		}, want: []string{"a/when", "a/option"}},
+		{name: "Case 6: all the problems at once", phases: map[string]Phase{
+			"a": {FnAlias: "", Node: "nodes", Dependency: []string{"b"}},
+			"b": {FnAlias: "work", Node: "missing", Dependency: []string{"a"}},
+		}, want: []string{"a/fn", "b/node", "/cycle"}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &Workflow{Name: "wkf", Phases: tt.phases}
+			err := wf.Validate(cfg, registry)
+			if len(tt.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			if assert.True(t, errors.As(err, &verr)) {
+				var got []string
+				for _, problem := range verr.Problems {
+					got = append(got, problem.Phase+"/"+problem.Kind)
+				}
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, "wkf", verr.WorkflowName)
+			}
+		})
+	}
+}
+
+// Name: TestValidateYaml
+func TestValidateYaml(t *testing.T) {
+	// create inputs for the test : a workflow YAML with a misspelled key
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	cfg := &viperx.Viperx{Viper: v}
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		return true, nil
+	}))
+	wf, err := LoadWorkflowBytes([]byte("name: wkf\nphases:\n  a:\n    fn: work\n    node: nodes\n    dependancy: [b]\n"))
+	assert.NoError(t, err)
+
+	err = wf.Validate(cfg, registry)
+	var verr *ValidationError
+	if assert.True(t, errors.As(err, &verr)) && assert.Len(t, verr.Problems, 1) {
+		assert.Equal(t, "yaml", verr.Problems[0].Kind)
+		assert.Contains(t, verr.Problems[0].Message, "dependancy")
+	}
+}
+
+// Name: TestFindCycleList
+func TestFindCycleList(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name   string              // test case name
+		depMap map[string][]string // the input : phase > dependencies
+		want   [][]string          // expected cycle paths
+	}{
+		{name: "Case 1: no cycle", depMap: map[string][]string{"a": nil, "b": {"a"}, "c": {"a", "b"}}, want: nil},
+		{name: "Case 2: self dependency", depMap: map[string][]string{"a": {"a"}}, want: [][]string{{"a", "a"}}},
+		{name: "Case 3: 2 phases", depMap: map[string][]string{"a": {"b"}, "b": {"a"}}, want: [][]string{{"a", "b", "a"}}},
+		{name: "Case 4: 3 phases", depMap: map[string][]string{"a": {"c"}, "b": {"a"}, "c": {"b"}}, want: [][]string{{"a", "c", "b", "a"}}},
+		{name: "Case 5: 2 cycles", depMap: map[string][]string{"a": {"b"}, "b": {"a"}, "c": {"d"}, "d": {"c"}}, want: [][]string{{"a", "b", "a"}, {"c", "d", "c"}}},
+		{name: "Case 6: unknown dependency is ignored", depMap: map[string][]string{"a": {"missing"}}, want: nil},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &Workflow{Name: "wkf", Phases: map[string]Phase{}}
+			for name, depList := range tt.depMap {
+				wf.Phases[name] = Phase{Dependency: depList}
+			}
+			assert.Equal(t, tt.want, wf.findCycleList())
+		})
+	}
+}
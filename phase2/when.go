@@ -0,0 +1,355 @@
+package phase2
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+)
+
+// Description: the status of a phase skipped because its when condition is false
+const statusSkippedCondition = "skipped (condition)"
+
+// Description: represents a parsed when expression
+//
+// Notes:
+// - evaluated against the workflow config
+type whenExpr interface {
+	eval(cfg *viperx.Viperx) bool
+}
+
+// Description: represents the when expressions nodes
+type (
+	whenOr    struct{ left, right whenExpr }
+	whenAnd   struct{ left, right whenExpr }
+	whenNot   struct{ expr whenExpr }
+	whenBool  struct{ key string }        // cni.enabled
+	whenEqual struct{ key, value string } // cni == cilium
+)
+
+// Description: represents a membership test (eg. cni in [cilium, calico])
+type whenIn struct {
+	key  string
+	list []string
+}
+
+func (e whenOr) eval(cfg *viperx.Viperx) bool  { return e.left.eval(cfg) || e.right.eval(cfg) }
+func (e whenAnd) eval(cfg *viperx.Viperx) bool { return e.left.eval(cfg) && e.right.eval(cfg) }
+func (e whenNot) eval(cfg *viperx.Viperx) bool { return !e.expr.eval(cfg) }
+
+func (e whenBool) eval(cfg *viperx.Viperx) bool {
+	switch v := cfg.Get(e.key).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		s := strings.ToLower(strings.TrimSpace(v))
+		return s != "" && s != "false" && s != "no" && s != "0"
+	case []any:
+		return len(v) > 0
+	case []string:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	default:
+		return fmt.Sprint(v) != "0"
+	}
+}
+
+func (e whenEqual) eval(cfg *viperx.Viperx) bool {
+	v := cfg.Get(e.key)
+	return v != nil && fmt.Sprint(v) == e.value
+}
+
+func (e whenIn) eval(cfg *viperx.Viperx) bool {
+	v := cfg.Get(e.key)
+	return v != nil && slices.Contains(e.list, fmt.Sprint(v))
+}
+
+// Description: parses a when expression
+//
+// Notes:
+// - grammar (lowest to highest precedence):
+//   - or:         a || b     (or: a or b)
+//   - and:        a && b     (or: a and b)
+//   - negation:   !a         (or: not a)
+//   - equality:   key == value, key != value
+//   - membership: key in [v1, v2], key not in [v1, v2]
+//   - boolean:    key
+//   - grouping:   ( ... )
+//
+// - a value is a bare word or a quoted string ("..." or '...')
+//
+// Example:
+//
+//	when: cni == cilium && !(cluster.ha || node.type in [worker, edge])
+func parseWhen(expression string) (whenExpr, error) {
+	tokenList, err := tokenizeWhen(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &whenParser{tokenList: tokenList}
+	expr, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.done() {
+		return nil, fmt.Errorf("unexpected %q at position %d", parser.peek().text, parser.pos+1)
+	}
+	return expr, nil
+}
+
+// Description: evaluates a when expression against a config
+//
+// Notes:
+// - an empty expression is true
+func evalWhen(expression string, cfg *viperx.Viperx) (bool, error) {
+	if strings.TrimSpace(expression) == "" {
+		return true, nil
+	}
+	if cfg == nil {
+		return false, fmt.Errorf("cfg is nil")
+	}
+	expr, err := parseWhen(expression)
+	if err != nil {
+		return false, fmt.Errorf("parsing when %q: %w", expression, err)
+	}
+	return expr.eval(cfg), nil
+}
+
+// Description: represents a token of a when expression
+type whenToken struct {
+	text   string
+	quoted bool // a quoted string is always a value
+}
+
+// Description: splits a when expression into tokens
+func tokenizeWhen(expression string) ([]whenToken, error) {
+	var tokenList []whenToken
+	runeList := []rune(expression)
+	for i := 0; i < len(runeList); {
+		r := runeList[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '[' || r == ']' || r == ',':
+			tokenList = append(tokenList, whenToken{text: string(r)})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runeList) && runeList[end] != r {
+				end++
+			}
+			if end == len(runeList) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokenList = append(tokenList, whenToken{text: string(runeList[i+1 : end]), quoted: true})
+			i = end + 1
+		case r == '&' || r == '|' || r == '=':
+			if i+1 >= len(runeList) || runeList[i+1] != r {
+				return nil, fmt.Errorf("unexpected %q at position %d", r, i+1)
+			}
+			tokenList = append(tokenList, whenToken{text: string([]rune{r, r})})
+			i += 2
+		case r == '!':
+			if i+1 < len(runeList) && runeList[i+1] == '=' {
+				tokenList = append(tokenList, whenToken{text: "!="})
+				i += 2
+				continue
+			}
+			tokenList = append(tokenList, whenToken{text: "!"})
+			i++
+		default:
+			end := i
+			for end < len(runeList) && !unicode.IsSpace(runeList[end]) && !strings.ContainsRune("()[],\"'&|=!", runeList[end]) {
+				end++
+			}
+			tokenList = append(tokenList, whenToken{text: string(runeList[i:end])})
+			i = end
+		}
+	}
+	return tokenList, nil
+}
+
+// Description: recursive descent parser of when expressions
+type whenParser struct {
+	tokenList []whenToken
+	pos       int
+}
+
+func (p *whenParser) done() bool { return p.pos >= len(p.tokenList) }
+
+func (p *whenParser) peek() whenToken {
+	if p.done() {
+		return whenToken{}
+	}
+	return p.tokenList[p.pos]
+}
+
+// Description: consumes the next token if it is one of the given operators
+func (p *whenParser) accept(opList ...string) bool {
+	token := p.peek()
+	if p.done() || token.quoted || !slices.Contains(opList, token.text) {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *whenParser) expect(op string) error {
+	if !p.accept(op) {
+		if p.done() {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q, got %q", op, p.peek().text)
+	}
+	return nil
+}
+
+// Description: returns the next token as a word (key or value)
+func (p *whenParser) word(what string) (string, error) {
+	token := p.peek()
+	if p.done() {
+		return "", fmt.Errorf("expected %s at end of expression", what)
+	}
+	if !token.quoted && (strings.ContainsAny(token.text, "()[],") || slices.Contains([]string{"&&", "||", "==", "!=", "!"}, token.text)) {
+		return "", fmt.Errorf("expected %s, got %q", what, token.text)
+	}
+	p.pos++
+	return token.text, nil
+}
+
+func (p *whenParser) parseOr() (whenExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = whenOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseAnd() (whenExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&", "and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = whenAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseUnary() (whenExpr, error) {
+	if p.accept("!", "not") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whenNot{expr: expr}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *whenParser) parseComparison() (whenExpr, error) {
+	key, err := p.word("a config key")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.accept("=="):
+		value, err := p.word("a value")
+		if err != nil {
+			return nil, err
+		}
+		return whenEqual{key: key, value: value}, nil
+	case p.accept("!="):
+		value, err := p.word("a value")
+		if err != nil {
+			return nil, err
+		}
+		return whenNot{expr: whenEqual{key: key, value: value}}, nil
+	case p.accept("in"):
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return whenIn{key: key, list: list}, nil
+	case p.peek().text == "not" && p.pos+1 < len(p.tokenList) && p.tokenList[p.pos+1].text == "in":
+		p.pos += 2
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return whenNot{expr: whenIn{key: key, list: list}}, nil
+	}
+	return whenBool{key: key}, nil
+}
+
+func (p *whenParser) parseList() ([]string, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var list []string
+	for !p.accept("]") {
+		if len(list) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		value, err := p.word("a value")
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// Description: evaluates the when condition of each phase against the config
+//
+// Return:
+// - the tiers where the phases whose condition is false are marked as skipped
+// - an error if a condition cannot be parsed
+//
+// Notes:
+// - a skipped phase stays in its tier: its dependents run as if it completed
+func applyWhen(tierList [][]Phase, cfg *viperx.Viperx, logger logx.Logger) ([][]Phase, error) {
+	for tierIdx, tier := range tierList {
+		for phaseIdx, phase := range tier {
+			ok, err := evalWhen(phase.When, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("phase %s > %w", phase.Name, err)
+			}
+			if !ok {
+				logger.Infof("⏭ %s > %s: %s", phase.Name, statusSkippedCondition, phase.When)
+				tierList[tierIdx][phaseIdx].skipReason = statusSkippedCondition
+			}
+		}
+	}
+	return tierList, nil
+}
@@ -0,0 +1,65 @@
+package phase2
+
+import (
+	"testing"
+
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestEvalWhen
+func TestEvalWhen(t *testing.T) {
+	// create inputs for the test : a config
+	v := viper.New()
+	v.Set("cni", "cilium")
+	v.Set("cluster.ha", true)
+	v.Set("cluster.nbNode", 3)
+	v.Set("feature.gpu", "false")
+	v.Set("node.type", "edge")
+	cfg := &viperx.Viperx{Viper: v}
+
+	// Define test cases.
+	tests := []struct {
+		name       string // test case name
+		expression string // the input
+		want       bool   // expected result
+		wantErr    bool   // expected error
+	}{
+		{name: "Case 1: empty expression", expression: "", want: true},
+		{name: "Case 2: equality", expression: "cni == cilium", want: true},
+		{name: "Case 3: inequality", expression: "cni != cilium", want: false},
+		{name: "Case 4: quoted value", expression: `cni == "cilium"`, want: true},
+		{name: "Case 5: number", expression: "cluster.nbNode == 3", want: true},
+		{name: "Case 6: membership", expression: "node.type in [worker, edge]", want: true},
+		{name: "Case 7: negated membership", expression: "node.type not in [worker, edge]", want: false},
+		{name: "Case 8: boolean key", expression: "cluster.ha", want: true},
+		{name: "Case 9: false string", expression: "feature.gpu", want: false},
+		{name: "Case 10: missing key", expression: "feature.missing", want: false},
+		{name: "Case 11: negation", expression: "!feature.gpu", want: true},
+		{name: "Case 12: and/or precedence", expression: "cni == calico || cluster.ha && node.type == edge", want: true},
+		{name: "Case 13: grouping", expression: "(cni == calico || cluster.ha) && not cluster.ha", want: false},
+		{name: "Case 14: keywords", expression: "cni == cilium and not feature.gpu or feature.missing", want: true},
+		{name: "Case 15: unbalanced parenthesis", expression: "(cni == cilium", wantErr: true},
+		{name: "Case 16: missing value", expression: "cni ==", wantErr: true},
+		{name: "Case 17: single ampersand", expression: "cni & cluster.ha", wantErr: true},
+		{name: "Case 18: unterminated string", expression: `cni == "cilium`, wantErr: true},
+		{name: "Case 19: trailing token", expression: "cni == cilium calico", wantErr: true},
+	}
+
+	// Iterate through the test cases
+	for _, tc := range tests {
+		// Run the function under test with the current test case data
+		t.Run(tc.name, func(t *testing.T) {
+			obtainedResult, err := evalWhen(tc.expression, cfg)
+
+			// Assertion for expected error state
+			if tc.wantErr {
+				assert.Error(t, err, "Expected to obtain an error, but got nil")
+				return
+			}
+			assert.NoError(t, err, "Obtained an unexpected error: %v", err)
+			assert.Equal(t, tc.want, obtainedResult, "Obtained result (%v) did not match expected result (%v)", obtainedResult, tc.want)
+		})
+	}
+}
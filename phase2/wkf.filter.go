@@ -0,0 +1,268 @@
+package phase2
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/abtransitionit/gocore/logx"
+)
+
+// Description: represents a set of phases selected by name or by tag
+//
+// Notes:
+// - Name: a phase name or an include prefix (selects all the phases of the group)
+// - Tag: selects the phases that have this tag
+// - Upstream: also selects everything the selected phases depend on (directly or not)
+// - Downstream: also selects everything that depends on the selected phases (directly or not)
+type PhaseSelector struct {
+	Name       string
+	Tag        string
+	Upstream   bool
+	Downstream bool
+}
+
+// Description: represents the phases of a workflow to run
+//
+// Notes:
+// - the zero value denotes no filtering (ie. all the phases)
+// - retained phases = all the phases (Retain is empty) or the phases selected by Retain, minus the phases selected by Skip
+// - unlike the -rN-M / -sN-M syntax, a selection does not depend on the position of the phases in the YAML
+type PhaseFilter struct {
+	Retain []PhaseSelector
+	Skip   []PhaseSelector
+}
+
+// Description: parses a phase filter
+//
+// Parameters:
+// - filter: a comma separated list of selectors
+//
+// Notes:
+// - name        : the phase (or the group of included phases)
+// - tag:name    : the phases that have the tag
+// - +selector   : the selected phases plus everything they depend on
+// - selector+   : the selected phases plus everything downstream
+// - !selector   : skip the selected phases
+//
+// Example Usage:
+//
+//	filter, err := phase2.ParsePhaseFilter("+kubeInit,tag:cni,!prep")
+func ParsePhaseFilter(filter string) (PhaseFilter, error) {
+	var phaseFilter PhaseFilter
+	for _, item := range strings.Split(filter, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		isSkip := strings.HasPrefix(item, "!")
+		item = strings.TrimPrefix(item, "!")
+
+		var selector PhaseSelector
+		if strings.HasPrefix(item, "+") {
+			selector.Upstream = true
+			item = item[1:]
+		}
+		if strings.HasSuffix(item, "+") {
+			selector.Downstream = true
+			item = item[:len(item)-1]
+		}
+		if tag, ok := strings.CutPrefix(item, "tag:"); ok {
+			selector.Tag = tag
+		} else {
+			selector.Name = item
+		}
+		if selector.Name == "" && selector.Tag == "" {
+			return PhaseFilter{}, fmt.Errorf("invalid phase filter %q: empty selector", filter)
+		}
+
+		if isSkip {
+			phaseFilter.Skip = append(phaseFilter.Skip, selector)
+		} else {
+			phaseFilter.Retain = append(phaseFilter.Retain, selector)
+		}
+	}
+	return phaseFilter, nil
+}
+
+// Description: reports whether the filter retains all the phases
+func (filter PhaseFilter) isEmpty() bool {
+	return len(filter.Retain) == 0 && len(filter.Skip) == 0
+}
+
+// Description: returns the filter with the syntax of ParsePhaseFilter
+func (filter PhaseFilter) String() string {
+	var itemList []string
+	for _, selector := range filter.Retain {
+		itemList = append(itemList, selector.String())
+	}
+	for _, selector := range filter.Skip {
+		itemList = append(itemList, "!"+selector.String())
+	}
+	return strings.Join(itemList, ",")
+}
+
+// Description: returns the selector with the syntax of ParsePhaseFilter
+func (selector PhaseSelector) String() string {
+	item := selector.Name
+	if selector.Tag != "" {
+		item = "tag:" + selector.Tag
+	}
+	if selector.Upstream {
+		item = "+" + item
+	}
+	if selector.Downstream {
+		item += "+"
+	}
+	return item
+}
+
+// Description: returns the phases selected by a selector
+//
+// Return:
+// - an error if the selector matches no phase (eg. a typo in a phase name)
+func (wf *Workflow) getSelected(selector PhaseSelector) (map[string]bool, error) {
+
+	// 1 - the phases that match the name or the tag
+	selectedSet := make(map[string]bool)
+	for name, phase := range wf.Phases {
+		switch {
+		case selector.Tag != "":
+			if slices.Contains(phase.Tags, selector.Tag) {
+				selectedSet[name] = true
+			}
+		case name == selector.Name, phase.group == selector.Name, strings.HasPrefix(phase.group, selector.Name+"."):
+			selectedSet[name] = true
+		}
+	}
+	if len(selectedSet) == 0 {
+		return nil, fmt.Errorf("phase filter %q matches no phase", selector.String())
+	}
+
+	// 2 - the dependency closure
+	closureSet := make(map[string]bool, len(selectedSet))
+	for name := range selectedSet {
+		closureSet[name] = true
+		if selector.Upstream {
+			for upstream := range wf.getUpstreamSet(name) {
+				closureSet[upstream] = true
+			}
+		}
+		if selector.Downstream {
+			for downstream := range wf.getDownstreamSet(name) {
+				closureSet[downstream] = true
+			}
+		}
+	}
+	return closureSet, nil
+}
+
+// Description: returns the phases that depend on a phase (directly or not)
+func (wf *Workflow) getDownstreamSet(phaseName string) map[string]bool {
+	dependentMap := make(map[string][]string)
+	for name, phase := range wf.Phases {
+		for _, dep := range phase.Dependency {
+			dependentMap[dep] = append(dependentMap[dep], name)
+		}
+	}
+	downstreamSet := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		for _, dependent := range dependentMap[name] {
+			if !downstreamSet[dependent] {
+				downstreamSet[dependent] = true
+				visit(dependent)
+			}
+		}
+	}
+	visit(phaseName)
+	return downstreamSet
+}
+
+// Description: filters the phases of a workflow with a PhaseFilter
+//
+// Parameters:
+// - tierList: the phases sorted by tier
+// - filter: the phases to retain and to skip
+//
+// Notes:
+// - the order of the tiers is kept, the empty tiers are removed
+func (wf *Workflow) applyFilter(tierList [][]Phase, filter PhaseFilter, logger logx.Logger) ([][]Phase, error) {
+	if filter.isEmpty() {
+		return tierList, nil
+	}
+
+	// log
+	logger.Infof("• workflow phases filtering activated with : %s", filter.String())
+
+	// 1 - get the retained phases
+	retainedSet := make(map[string]bool)
+	if len(filter.Retain) == 0 {
+		for name := range wf.Phases {
+			retainedSet[name] = true
+		}
+	}
+	for _, selector := range filter.Retain {
+		selectedSet, err := wf.getSelected(selector)
+		if err != nil {
+			return nil, err
+		}
+		for name := range selectedSet {
+			retainedSet[name] = true
+		}
+	}
+
+	// 2 - remove the skipped phases
+	for _, selector := range filter.Skip {
+		selectedSet, err := wf.getSelected(selector)
+		if err != nil {
+			return nil, err
+		}
+		for name := range selectedSet {
+			delete(retainedSet, name)
+		}
+	}
+
+	// 3 - filter the tiers
+	var filtered [][]Phase
+	for _, tier := range tierList {
+		var newTier []Phase
+		for _, phase := range tier {
+			if retainedSet[phase.Name] {
+				newTier = append(newTier, phase)
+			}
+		}
+		if len(newTier) > 0 {
+			filtered = append(filtered, newTier)
+		}
+	}
+	return filtered, nil
+}
+
+// Description: warns about the retained phases that have a dependency that is not retained
+//
+// Notes:
+// - the phase runs without its dependency: the dependency is expected to be complete (eg. from a previous run)
+func (wf *Workflow) warnSkippedUpstream(tierList [][]Phase, logger logx.Logger) {
+	retainedSet := make(map[string]bool)
+	for _, tier := range tierList {
+		for _, phase := range tier {
+			retainedSet[phase.Name] = true
+		}
+	}
+	for _, tier := range tierList {
+		for _, phase := range tier {
+			var skippedList []string
+			for _, dep := range phase.Dependency {
+				if !retainedSet[dep] {
+					skippedList = append(skippedList, dep)
+				}
+			}
+			if len(skippedList) > 0 {
+				sort.Strings(skippedList)
+				logger.Warnf("⚠ %s > retained but depends on phase(s) that are not retained: %v", phase.Name, skippedList)
+			}
+		}
+	}
+}
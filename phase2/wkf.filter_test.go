@@ -0,0 +1,68 @@
+package phase2
+
+import (
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestApplyFilter
+func TestApplyFilter(t *testing.T) {
+	// create inputs for the test : a workflow a -> b -> c -> d and a group
+	logger := logx.GetLogger()
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a":         {Tags: []string{"os"}},
+		"b":         {Dependency: []string{"a"}},
+		"c":         {Dependency: []string{"b"}, Tags: []string{"cni"}},
+		"d":         {Dependency: []string{"c"}},
+		"prep.x":    {group: "prep"},
+		"prep.os.y": {group: "prep.os", Dependency: []string{"prep.x"}},
+	}}
+	tierList, err := wf.TopoSortByTier(logger)
+	assert.NoError(t, err)
+
+	// Define test cases.
+	tests := []struct {
+		name    string   // test case name
+		filter  string   // the input
+		want    []string // expected retained phases (in tier order)
+		wantErr bool     // expected error
+	}{
+		{name: "Case 1: no filter", filter: "", want: []string{"a", "prep.x", "b", "prep.os.y", "c", "d"}},
+		{name: "Case 2: name", filter: "c", want: []string{"c"}},
+		{name: "Case 3: upstream closure", filter: "+c", want: []string{"a", "b", "c"}},
+		{name: "Case 4: downstream closure", filter: "b+", want: []string{"b", "c", "d"}},
+		{name: "Case 5: tag", filter: "tag:cni", want: []string{"c"}},
+		{name: "Case 6: tag with downstream closure and skip", filter: "tag:os+,!d", want: []string{"a", "b", "c"}},
+		{name: "Case 7: group", filter: "prep", want: []string{"prep.x", "prep.os.y"}},
+		{name: "Case 8: skip only", filter: "!prep", want: []string{"a", "b", "c", "d"}},
+		{name: "Case 9: unknown phase", filter: "zz", wantErr: true},
+		{name: "Case 10: empty selector", filter: "!", wantErr: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParsePhaseFilter(tt.filter)
+			if err == nil {
+				var filtered [][]Phase
+				filtered, err = wf.applyFilter(tierList, filter, logger)
+				var got []string
+				for _, tier := range filtered {
+					for _, phase := range tier {
+						got = append(got, phase.Name)
+					}
+				}
+				if !tt.wantErr {
+					assert.Equal(t, tt.want, got)
+				}
+			}
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
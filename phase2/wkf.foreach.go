@@ -0,0 +1,158 @@
+package phase2
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+)
+
+// Description: the param of a forEach phase that is replaced by the item
+const forEachParam = "item"
+
+// Description: represents the item of a phase expanded from a forEach phase
+type forEachItem struct {
+	index int // 1-based
+	value any
+}
+
+// Description: the characters that cannot be part of a generated phase name
+var forEachNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Description: returns the name of the phase generated for an item
+//
+// Notes:
+// - <phase>.<item> for a scalar item (eg. installCli.helm)
+// - <phase>.<index> otherwise (eg. installCli.2)
+func getForEachName(phaseName string, item forEachItem) string {
+	suffix := ""
+	switch v := item.value.(type) {
+	case string, bool, int, int64, float64:
+		suffix = forEachNameRegexp.ReplaceAllString(fmt.Sprint(v), "_")
+	}
+	if suffix == "" {
+		suffix = strconv.Itoa(item.index)
+	}
+	return phaseName + "." + suffix
+}
+
+// Description: returns the items of a forEach phase
+//
+// Notes:
+// - the config key must be a list
+func getForEachItemList(key string, cfg *viperx.Viperx) ([]forEachItem, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("forEach %q > cfg is nil", key)
+	}
+	value := cfg.Get(key)
+	var valueList []any
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("forEach %q > not found in config", key)
+	case []any:
+		valueList = v
+	case []string:
+		for _, item := range v {
+			valueList = append(valueList, item)
+		}
+	default:
+		return nil, fmt.Errorf("forEach %q > is not a list (%T)", key, value)
+	}
+	itemList := make([]forEachItem, len(valueList))
+	for i, item := range valueList {
+		itemList[i] = forEachItem{index: i + 1, value: item}
+	}
+	return itemList, nil
+}
+
+// Description: returns a copy of the workflow where each forEach phase is expanded into one phase per item
+//
+// Parameters:
+// - cfg: the workflow config (contains the lists)
+//
+// Notes:
+// - the generated phases share the dependencies of the forEach phase and run concurrently
+// - a phase that depends on the forEach phase depends on all the generated phases
+// - the param "item" is replaced by the item, the item is appended to the params if "item" is not a param
+// - the generated phases form a group named after the forEach phase (a filter on the name selects them all)
+// - a forEach phase with an empty list is removed (its dependents no longer depend on it)
+// - the workflow is returned as is if it has no forEach phase
+func (wf *Workflow) expandForEach(cfg *viperx.Viperx, logger logx.Logger) (*Workflow, error) {
+
+	// 1 - get the forEach phases - in a deterministic order
+	var nameList []string
+	for name, phase := range wf.Phases {
+		if phase.ForEach != "" {
+			nameList = append(nameList, name)
+		}
+	}
+	if len(nameList) == 0 {
+		return wf, nil
+	}
+	sort.Strings(nameList)
+
+	// 2 - copy the workflow
+	expanded := *wf
+	expanded.Phases = make(map[string]Phase, len(wf.Phases))
+	for name, phase := range wf.Phases {
+		expanded.Phases[name] = phase
+	}
+
+	// 3 - expand the forEach phases
+	generatedMap := make(map[string][]string) // forEach phase > generated phases
+	for _, name := range nameList {
+		phase := expanded.Phases[name]
+		itemList, err := getForEachItemList(phase.ForEach, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("phase %s > %w", name, err)
+		}
+		delete(expanded.Phases, name)
+		if len(itemList) == 0 {
+			logger.Warnf("⚠ %s > forEach %q is empty > phase removed", name, phase.ForEach)
+		}
+
+		var generatedList []string
+		for _, item := range itemList {
+			generatedName := getForEachName(name, item)
+			if _, ok := expanded.Phases[generatedName]; ok {
+				return nil, fmt.Errorf("phase %s > forEach %q > generated phase %q collides with an existing phase", name, phase.ForEach, generatedName)
+			}
+			generated := phase
+			generated.ForEach = ""
+			generated.item = &forEachItem{index: item.index, value: item.value}
+			generated.group = name
+			generated.Dependency = append([]string(nil), phase.Dependency...)
+			if !slices.Contains(phase.Param, forEachParam) {
+				generated.Param = append(append([]string(nil), phase.Param...), forEachParam)
+			}
+			expanded.Phases[generatedName] = generated
+			generatedList = append(generatedList, generatedName)
+		}
+		generatedMap[name] = generatedList
+		logger.Debugf("↪ %s > forEach %q > %d phase(s): %v", name, phase.ForEach, len(generatedList), generatedList)
+	}
+
+	// 4 - replace the dependencies on a forEach phase by the generated phases
+	for name, phase := range expanded.Phases {
+		var depList []string
+		changed := false
+		for _, dep := range phase.Dependency {
+			if generatedList, ok := generatedMap[dep]; ok {
+				depList = append(depList, generatedList...)
+				changed = true
+				continue
+			}
+			depList = append(depList, dep)
+		}
+		if changed {
+			phase.Dependency = depList
+			expanded.Phases[name] = phase
+		}
+	}
+
+	return &expanded, nil
+}
@@ -0,0 +1,142 @@
+package phase2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/viperx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestGetForEachName
+func TestGetForEachName(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name string      // test case name
+		item forEachItem // the input
+		want string      // expected phase name
+	}{
+		{name: "Case 1: string item", item: forEachItem{index: 1, value: "helm"}, want: "installCli.helm"},
+		{name: "Case 2: invalid characters", item: forEachItem{index: 1, value: "kube ctl/1.30"}, want: "installCli.kube_ctl_1_30"},
+		{name: "Case 3: number item", item: forEachItem{index: 2, value: 42}, want: "installCli.42"},
+		{name: "Case 4: map item", item: forEachItem{index: 3, value: map[string]any{"name": "helm"}}, want: "installCli.3"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getForEachName("installCli", tt.item))
+		})
+	}
+}
+
+// Name: TestExpandForEach
+func TestExpandForEach(t *testing.T) {
+	// create inputs for the test : a config with lists and a workflow prep -> install (forEach) -> verify
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	v.Set("cliList", []string{"helm", "kubectl"})
+	v.Set("emptyList", []string{})
+	v.Set("version", "1.2")
+	cfg := &viperx.Viperx{Viper: v}
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"prep":    {FnAlias: "work", Node: "nodes"},
+		"install": {FnAlias: "work", Node: "nodes", ForEach: "cliList", Param: []string{"version"}, Dependency: []string{"prep"}},
+		"verify":  {FnAlias: "work", Node: "nodes", Dependency: []string{"install"}},
+		"empty":   {FnAlias: "work", Node: "nodes", ForEach: "emptyList"},
+		"after":   {FnAlias: "work", Node: "nodes", Dependency: []string{"empty", "prep"}},
+	}}
+
+	expanded, err := wf.expandForEach(cfg, logger)
+	assert.NoError(t, err)
+	assert.Len(t, wf.Phases, 5) // the workflow is not modified
+
+	// Define test cases.
+	tests := []struct {
+		name      string   // test case name
+		phase     string   // the input
+		wantDep   []string // expected dependencies
+		wantParam []string // expected params
+		wantItem  any      // expected item (nil if none)
+	}{
+		{name: "Case 1: generated phase", phase: "install.helm", wantDep: []string{"prep"}, wantParam: []string{"version", "item"}, wantItem: "helm"},
+		{name: "Case 2: other generated phase", phase: "install.kubectl", wantDep: []string{"prep"}, wantParam: []string{"version", "item"}, wantItem: "kubectl"},
+		{name: "Case 3: dependency on the forEach phase", phase: "verify", wantDep: []string{"install.helm", "install.kubectl"}},
+		{name: "Case 4: dependency on an empty forEach phase is removed", phase: "after", wantDep: []string{"prep"}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase, ok := expanded.Phases[tt.phase]
+			if !assert.True(t, ok, "phase %q not found", tt.phase) {
+				return
+			}
+			assert.Equal(t, tt.wantDep, phase.Dependency)
+			assert.ElementsMatch(t, tt.wantParam, phase.Param)
+			if tt.wantItem == nil {
+				assert.Nil(t, phase.item)
+			} else if assert.NotNil(t, phase.item) {
+				assert.Equal(t, tt.wantItem, phase.item.value)
+				assert.Equal(t, "install", phase.group)
+			}
+		})
+	}
+	nameList := make([]string, 0, len(expanded.Phases))
+	for name := range expanded.Phases {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+	assert.Equal(t, []string{"after", "install.helm", "install.kubectl", "prep", "verify"}, nameList)
+
+	// a list that is missing or collides with an existing phase is an error
+	wf.Phases["install"] = Phase{FnAlias: "work", Node: "nodes", ForEach: "missingList"}
+	_, err = wf.expandForEach(cfg, logger)
+	assert.ErrorContains(t, err, "not found in config")
+	wf.Phases["install"] = Phase{FnAlias: "work", Node: "nodes", ForEach: "cliList"}
+	wf.Phases["install.helm"] = Phase{FnAlias: "work", Node: "nodes"}
+	_, err = wf.expandForEach(cfg, logger)
+	assert.ErrorContains(t, err, "collides")
+}
+
+// Name: TestForEachRun
+func TestForEachRun(t *testing.T) {
+	// create inputs for the test : a forEach phase whose param "itemList" is a config key that contains "item"
+	logger := logx.GetLogger()
+	v := viper.New()
+	v.Set("nodes", []string{"h1"})
+	v.Set("cliList", []string{"helm", "kubectl"})
+	v.Set("itemList", "all")
+	cfg := &viperx.Viperx{Viper: v}
+	var mu sync.Mutex
+	var gotList []string // the params received
+	registry := NewFnRegistry()
+	assert.NoError(t, registry.Add("wkf", "work", func(phaseName, target string, params [][]any, logger logx.Logger) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotList = append(gotList, fmt.Sprintf("%s:%v", phaseName, params))
+		return true, nil
+	}))
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"install": {FnAlias: "work", Node: "nodes", ForEach: "cliList", Param: []string{"itemList", "item"}},
+	}}
+
+	// each generated phase receives its item
+	_, err := wf.ExecuteWithOption(context.Background(), cfg, registry, "", ExecOption{}, logger)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"install.helm:[[all] [helm]]", "install.kubectl:[[all] [kubectl]]"}, gotList)
+
+	// the view shows the item in place of the param "item" only
+	expanded, tierList, err := wf.getRunTierList(cfg, "", ExecOption{}, logger)
+	assert.NoError(t, err)
+	view, err := expanded.GetTierView(tierList, logger)
+	assert.NoError(t, err)
+	assert.Contains(t, view, "itemList, item=helm")
+	assert.Contains(t, view, "itemList, item=kubectl")
+}
@@ -0,0 +1,205 @@
+package phase2
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/abtransitionit/gocore/logx"
+)
+
+// Description: represents the colors of a phase status in a graph
+type graphColor struct {
+	fill   string
+	stroke string
+}
+
+// Description: maps a phase status (from a run report) to its colors
+var graphColorMap = map[string]graphColor{
+	"success":   {fill: "#c8e6c9", stroke: "#2e7d32"},
+	"failed":    {fill: "#ffcdd2", stroke: "#c62828"},
+	"cancelled": {fill: "#ffe0b2", stroke: "#ef6c00"},
+	"skipped":   {fill: "#eeeeee", stroke: "#9e9e9e"},
+}
+
+// Description: returns the status of each phase from a run report
+//
+// Notes:
+// - a phase failed if it failed (or timed out) on at least one host
+// - a phase is cancelled if it is cancelled on at least one host (and failed on none)
+// - a phase is skipped if its when condition is false
+// - a phase succeeded if it succeeded on all its hosts
+func getPhaseStatusMap(report *RunReport) map[string]string {
+	statusMap := make(map[string]string)
+	if report == nil {
+		return statusMap
+	}
+	for _, hostReport := range report.Hosts {
+		current := statusMap[hostReport.Phase]
+		switch hostReport.Status {
+		case "failed", "timeout":
+			statusMap[hostReport.Phase] = "failed"
+		case "cancelled", "skipped (aborted)":
+			if current != "failed" {
+				statusMap[hostReport.Phase] = "cancelled"
+			}
+		case statusSkippedCondition, statusSkippedNoCheck:
+			if current == "" {
+				statusMap[hostReport.Phase] = "skipped"
+			}
+		default: // success, unchanged, would change, success (resumed)
+			if current == "" {
+				statusMap[hostReport.Phase] = "success"
+			}
+		}
+	}
+	return statusMap
+}
+
+// Description: returns the lines of the label of a phase node
+func getGraphLabelList(phase Phase) []string {
+	fn := phase.FnAlias
+	if fn == "" {
+		fn = "none"
+	}
+	node := phase.Node
+	if node == "" {
+		node = "none"
+	}
+	return []string{phase.Name, "fn: " + fn, "node: " + node}
+}
+
+// Description: returns the edges (dependency -> phase) of the workflow in a deterministic order
+func (wf *Workflow) getGraphEdgeList(tierList [][]Phase) [][2]string {
+	var edgeList [][2]string
+	for _, tier := range tierList {
+		for _, phase := range tier {
+			depList := append([]string(nil), phase.Dependency...)
+			sort.Strings(depList)
+			for _, dep := range depList {
+				edgeList = append(edgeList, [2]string{dep, phase.Name})
+			}
+		}
+	}
+	return edgeList
+}
+
+// Description: returns the dependency graph of the workflow as a Graphviz DOT digraph
+//
+// Parameters:
+// - report: a previous run report used to color the phases (optional)
+//
+// Notes:
+// - each tier is a cluster whose phases share the same rank
+// - a node label shows the phase name, its fn alias and its target node
+//
+// Example Usage:
+//
+//	dot, err := workflow.GetDotView(nil, logger)
+//	// dot -Tsvg workflow.dot > workflow.svg
+func (wf *Workflow) GetDotView(report *RunReport, logger logx.Logger) (string, error) {
+	tierList, err := wf.TopoSortByTier(logger)
+	if err != nil {
+		return "", fmt.Errorf("cannot sort tiers: %w", err)
+	}
+	statusMap := getPhaseStatusMap(report)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", wf.Name)
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"white\"];\n")
+
+	// nodes: one cluster per tier
+	for tierIndex, tier := range tierList {
+		tierID := tierIndex + 1
+		fmt.Fprintf(&b, "  subgraph \"cluster_tier%d\" {\n", tierID)
+		fmt.Fprintf(&b, "    label=\"Tier %d\";\n", tierID)
+		b.WriteString("    rank=same;\n")
+		for _, phase := range tier {
+			label := strings.Join(getGraphLabelList(phase), "\n")
+			attr := fmt.Sprintf("label=%q", label) // a line break is quoted as \n: a DOT line break
+			if color, ok := graphColorMap[statusMap[phase.Name]]; ok {
+				attr += fmt.Sprintf(", fillcolor=%q, color=%q", color.fill, color.stroke)
+			}
+			fmt.Fprintf(&b, "    %q [%s];\n", phase.Name, attr)
+		}
+		b.WriteString("  }\n")
+	}
+
+	// edges
+	for _, edge := range wf.getGraphEdgeList(tierList) {
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge[0], edge[1])
+	}
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// Description: matches the characters that are not allowed in a Mermaid node id
+var mermaidIdRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Description: returns the Mermaid id of a phase
+func getMermaidId(phaseName string) string {
+	return "p_" + mermaidIdRegexp.ReplaceAllString(phaseName, "_")
+}
+
+// Description: returns the dependency graph of the workflow as a Mermaid flowchart
+//
+// Parameters:
+// - report: a previous run report used to color the phases (optional)
+//
+// Notes:
+// - each tier is a subgraph
+// - a node label shows the phase name, its fn alias and its target node
+func (wf *Workflow) GetMermaidView(report *RunReport, logger logx.Logger) (string, error) {
+	tierList, err := wf.TopoSortByTier(logger)
+	if err != nil {
+		return "", fmt.Errorf("cannot sort tiers: %w", err)
+	}
+	statusMap := getPhaseStatusMap(report)
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	// nodes: one subgraph per tier
+	for tierIndex, tier := range tierList {
+		tierID := tierIndex + 1
+		fmt.Fprintf(&b, "  subgraph tier%d [\"Tier %d\"]\n", tierID, tierID)
+		for _, phase := range tier {
+			label := strings.ReplaceAll(strings.Join(getGraphLabelList(phase), "<br/>"), `"`, "#quot;")
+			fmt.Fprintf(&b, "    %s[\"%s\"]\n", getMermaidId(phase.Name), label)
+		}
+		b.WriteString("  end\n")
+	}
+
+	// edges
+	for _, edge := range wf.getGraphEdgeList(tierList) {
+		fmt.Fprintf(&b, "  %s --> %s\n", getMermaidId(edge[0]), getMermaidId(edge[1]))
+	}
+
+	// colors
+	statusList := make([]string, 0, len(graphColorMap))
+	for status := range graphColorMap {
+		statusList = append(statusList, status)
+	}
+	sort.Strings(statusList)
+	for _, status := range statusList {
+		var idList []string
+		for _, tier := range tierList {
+			for _, phase := range tier {
+				if statusMap[phase.Name] == status {
+					idList = append(idList, getMermaidId(phase.Name))
+				}
+			}
+		}
+		if len(idList) == 0 {
+			continue
+		}
+		color := graphColorMap[status]
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", status, color.fill, color.stroke)
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(idList, ","), status)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,140 @@
+package phase2
+
+import (
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+// Description: returns a workflow a -> b, a -> c-1 and the report of a run where b failed on one host
+func getTestGraphWorkflow() (*Workflow, *RunReport) {
+	wf := &Workflow{Name: "wkf", Phases: map[string]Phase{
+		"a":   {FnAlias: "install", Node: "nodes"},
+		"b":   {FnAlias: "configure", Node: "nodes", Dependency: []string{"a"}},
+		"c-1": {Dependency: []string{"a"}},
+	}}
+	report := &RunReport{Hosts: []HostReport{
+		{Phase: "a", Host: "h1", Status: "success"},
+		{Phase: "a", Host: "h2", Status: "unchanged"},
+		{Phase: "b", Host: "h1", Status: "success"},
+		{Phase: "b", Host: "h2", Status: "timeout"},
+		{Phase: "c-1", Host: "h1", Status: statusSkippedCondition},
+	}}
+	return wf, report
+}
+
+// Name: TestGetDotView
+func TestGetDotView(t *testing.T) {
+	logger := logx.GetLogger()
+	wf, report := getTestGraphWorkflow()
+
+	// Define test cases.
+	tests := []struct {
+		name   string     // test case name
+		report *RunReport // the input
+		want   string     // expected DOT
+	}{
+		{name: "Case 1: no report", report: nil, want: `digraph "wkf" {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fillcolor="white"];
+  subgraph "cluster_tier1" {
+    label="Tier 1";
+    rank=same;
+    "a" [label="a\nfn: install\nnode: nodes"];
+  }
+  subgraph "cluster_tier2" {
+    label="Tier 2";
+    rank=same;
+    "b" [label="b\nfn: configure\nnode: nodes"];
+    "c-1" [label="c-1\nfn: none\nnode: none"];
+  }
+  "a" -> "b";
+  "a" -> "c-1";
+}
+`},
+		{name: "Case 2: colored by the report", report: report, want: `digraph "wkf" {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fillcolor="white"];
+  subgraph "cluster_tier1" {
+    label="Tier 1";
+    rank=same;
+    "a" [label="a\nfn: install\nnode: nodes", fillcolor="#c8e6c9", color="#2e7d32"];
+  }
+  subgraph "cluster_tier2" {
+    label="Tier 2";
+    rank=same;
+    "b" [label="b\nfn: configure\nnode: nodes", fillcolor="#ffcdd2", color="#c62828"];
+    "c-1" [label="c-1\nfn: none\nnode: none", fillcolor="#eeeeee", color="#9e9e9e"];
+  }
+  "a" -> "b";
+  "a" -> "c-1";
+}
+`},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wf.GetDotView(tt.report, logger)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Name: TestGetMermaidView
+func TestGetMermaidView(t *testing.T) {
+	logger := logx.GetLogger()
+	wf, report := getTestGraphWorkflow()
+
+	// Define test cases.
+	tests := []struct {
+		name   string     // test case name
+		report *RunReport // the input
+		want   string     // expected Mermaid
+	}{
+		{name: "Case 1: no report", report: nil, want: `flowchart LR
+  subgraph tier1 ["Tier 1"]
+    p_a["a<br/>fn: install<br/>node: nodes"]
+  end
+  subgraph tier2 ["Tier 2"]
+    p_b["b<br/>fn: configure<br/>node: nodes"]
+    p_c_1["c-1<br/>fn: none<br/>node: none"]
+  end
+  p_a --> p_b
+  p_a --> p_c_1
+`},
+		{name: "Case 2: colored by the report", report: report, want: `flowchart LR
+  subgraph tier1 ["Tier 1"]
+    p_a["a<br/>fn: install<br/>node: nodes"]
+  end
+  subgraph tier2 ["Tier 2"]
+    p_b["b<br/>fn: configure<br/>node: nodes"]
+    p_c_1["c-1<br/>fn: none<br/>node: none"]
+  end
+  p_a --> p_b
+  p_a --> p_c_1
+  classDef failed fill:#ffcdd2,stroke:#c62828
+  class p_b failed
+  classDef skipped fill:#eeeeee,stroke:#9e9e9e
+  class p_c_1 skipped
+  classDef success fill:#c8e6c9,stroke:#2e7d32
+  class p_a success
+`},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wf.GetMermaidView(tt.report, logger)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// a cycle cannot be drawn
+	wf.Phases["a"] = Phase{Dependency: []string{"b"}}
+	_, err := wf.GetMermaidView(nil, logger)
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package phase2
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Description: represents the inclusion of another workflow file
+//
+// Notes:
+// - the phases of the included file become phases of the workflow, named <prefix>.<phase>
+// - an outer phase can depend on the included group as a whole (dependency: [<prefix>])
+// - the fn aliases of the included phases are resolved in the registry of the including workflow
+//
+// Example Usage:
+//
+//	include:
+//	  - file: prepare.yaml   # relative to the including file
+//	    prefix: prep         # prep.updateOs, prep.installContainerd, ...
+//	    dependency: [checkSsh]
+//	phases:
+//	  kubeInit:
+//	    dependency: [prep]   # depends on all the phases of prepare.yaml
+type Include struct {
+	File       string   `yaml:"file"`                 // the path of the included file (relative to the including file)
+	Prefix     string   `yaml:"prefix,omitempty"`     // the prefix of the included phases (default: the name of the included workflow)
+	Dependency []string `yaml:"dependency,omitempty"` // the phases the included root phases depend on
+}
+
+// Description: reads the included files of a workflow
+//
+// Notes:
+// - read: reads a file from its path
+// - join: returns the path of an included file from the path of the including file
+type includeReader struct {
+	read func(filePath string) ([]byte, error)
+	join func(parent, name string) string
+}
+
+// Description: returns a reader of included files on the local file system
+//
+// Notes:
+// - an included file is relative to the folder of the including file (the working dir when there is none)
+func getOsIncludeReader() includeReader {
+	return includeReader{
+		read: os.ReadFile,
+		join: func(parent, name string) string {
+			if filepath.IsAbs(name) {
+				return name
+			}
+			return filepath.Join(filepath.Dir(parent), name)
+		},
+	}
+}
+
+// Description: returns a reader of included files in a file system (eg. an embed.FS)
+func getFsIncludeReader(fsys fs.FS) includeReader {
+	return includeReader{
+		read: func(filePath string) ([]byte, error) { return fs.ReadFile(fsys, filePath) },
+		join: func(parent, name string) string { return path.Join(path.Dir(parent), name) },
+	}
+}
+
+// Description: adds the phases of the included files to the workflow
+//
+// Parameters:
+// - parent: the path of the workflow file (empty if the workflow is not loaded from a file)
+// - reader: reads the included files
+// - chain: the files being included (detects include cycles)
+//
+// Return:
+// - an error that lists all the problems (unreadable file, include cycle, name collision)
+//
+// Notes:
+// - includes are expanded recursively: an included file can include other files
+// - a dependency on a group is replaced by the leaf phases of the group (ie. the phases no other phase of the group depends on)
+func (wf *Workflow) expandInclude(parent string, reader includeReader, chain []string) error {
+	if len(wf.Include) == 0 {
+		return nil
+	}
+	if wf.Phases == nil {
+		wf.Phases = make(map[string]Phase)
+	}
+	wf.includeMap = make(map[string][]byte)
+	wf.groupMap = make(map[string][]string)
+	var errList []error
+
+	// 1 - loop over the included files
+	for _, include := range wf.Include {
+
+		// 11 - read the file
+		if include.File == "" {
+			errList = append(errList, fmt.Errorf("include: file is empty"))
+			continue
+		}
+		includePath := reader.join(parent, include.File)
+		if slices.Contains(chain, includePath) {
+			errList = append(errList, fmt.Errorf("include %q: cycle: %s", include.File, strings.Join(append(chain, includePath), " -> ")))
+			continue
+		}
+		data, err := reader.read(includePath)
+		if err != nil {
+			// not wrapped: a missing included file must not look like a missing workflow file
+			errList = append(errList, fmt.Errorf("include %q: %v", include.File, err))
+			continue
+		}
+		sub, err := parseWorkflow(data)
+		if err != nil {
+			errList = append(errList, fmt.Errorf("include %q: %w", include.File, err))
+			continue
+		}
+		if err := sub.expandInclude(includePath, reader, append(chain, includePath)); err != nil {
+			errList = append(errList, fmt.Errorf("include %q: %w", include.File, err))
+			continue
+		}
+
+		// 12 - check the prefix
+		prefix := include.Prefix
+		if prefix == "" {
+			prefix = sub.Name
+		}
+		if prefix == "" {
+			errList = append(errList, fmt.Errorf("include %q: prefix is empty and the included workflow has no name", include.File))
+			continue
+		}
+		if _, ok := wf.Phases[prefix]; ok {
+			errList = append(errList, fmt.Errorf("include %q: prefix %q collides with phase %q", include.File, prefix, prefix))
+			continue
+		}
+		if _, ok := wf.groupMap[prefix]; ok {
+			errList = append(errList, fmt.Errorf("include %q: prefix %q is already used by another include", include.File, prefix))
+			continue
+		}
+
+		// 13 - add the phases - in a deterministic order
+		nameList := make([]string, 0, len(sub.Phases))
+		for name := range sub.Phases {
+			nameList = append(nameList, name)
+		}
+		sort.Strings(nameList)
+
+		isDependency := make(map[string]bool)
+		for _, name := range nameList {
+			for _, dep := range sub.Phases[name].Dependency {
+				isDependency[dep] = true
+			}
+		}
+
+		var leafList []string
+		for _, name := range nameList {
+			phase := sub.Phases[name]
+			fullName := prefix + "." + name
+			if _, ok := wf.Phases[fullName]; ok {
+				errList = append(errList, fmt.Errorf("include %q: phase %q collides with an existing phase", include.File, fullName))
+				continue
+			}
+
+			// dependencies: inside the group, or on the outer phases for the root phases
+			depList := make([]string, 0, len(phase.Dependency)+len(include.Dependency))
+			for _, dep := range phase.Dependency {
+				depList = append(depList, prefix+"."+dep)
+			}
+			if len(phase.Dependency) == 0 {
+				depList = append(depList, include.Dependency...)
+			}
+			phase.Dependency = depList
+
+			// params: the outputs of the group phases
+			paramList := make([]string, len(phase.Param))
+			for i, param := range phase.Param {
+				paramList[i] = param
+				if ref, isOutput, err := parseOutputRef(param, sub.Phases); isOutput && err == nil {
+					if _, ok := sub.Phases[ref.phaseName]; ok {
+						paramList[i] = outputParamPrefix + prefix + "." + strings.TrimPrefix(param, outputParamPrefix)
+					}
+				}
+			}
+			phase.Param = paramList
+
+			// options inherited from the included workflow
+			if phase.Timeout == 0 {
+				phase.Timeout = sub.Timeout
+			}
+			if phase.group == "" {
+				phase.group = prefix
+			} else {
+				phase.group = prefix + "." + phase.group
+			}
+
+			wf.Phases[fullName] = phase
+			if !isDependency[name] {
+				leafList = append(leafList, fullName)
+			}
+		}
+
+		// 14 - keep the group and the sources
+		wf.groupMap[prefix] = leafList
+		for subPrefix, subLeafList := range sub.groupMap {
+			fullLeafList := make([]string, len(subLeafList))
+			for i, leaf := range subLeafList {
+				fullLeafList[i] = prefix + "." + leaf
+			}
+			wf.groupMap[prefix+"."+subPrefix] = fullLeafList
+		}
+		wf.includeMap[includePath] = data
+		for subPath, subData := range sub.includeMap {
+			wf.includeMap[subPath] = subData
+		}
+	}
+
+	// 2 - replace the dependencies on a group by the leaf phases of the group
+	for name, phase := range wf.Phases {
+		var depList []string
+		changed := false
+		for _, dep := range phase.Dependency {
+			leafList, isGroup := wf.groupMap[dep]
+			if _, isPhase := wf.Phases[dep]; isGroup && !isPhase {
+				depList = append(depList, leafList...)
+				changed = true
+				continue
+			}
+			depList = append(depList, dep)
+		}
+		if changed {
+			phase.Dependency = depList
+			wf.Phases[name] = phase
+		}
+	}
+
+	return errors.Join(errList...)
+}
@@ -0,0 +1,110 @@
+package phase2
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestExpandInclude
+func TestExpandInclude(t *testing.T) {
+	// create inputs for the test : a workflow that includes a group of 3 phases a -> b, c
+	fsys := fstest.MapFS{
+		"main.yaml": {Data: []byte(`name: main
+include:
+  - file: sub/prepare.yaml
+    prefix: prep
+    dependency: [checkSsh]
+  - file: sub/cni.yaml
+phases:
+  checkSsh:
+    fn: check
+  kubeInit:
+    fn: init
+    dependency: [prep]
+    param: [outputs.prep.b.h1.token]
+`)},
+		"sub/prepare.yaml": {Data: []byte(`name: prepare
+timeout: 30s
+phases:
+  a:
+    fn: update
+  b:
+    fn: install
+    dependency: [a]
+    param: [outputs.a.h1.url, version]
+  c:
+    fn: other
+    timeout: 5s
+`)},
+		"sub/cni.yaml": {Data: []byte(`name: cni
+phases:
+  install:
+    fn: cilium
+`)},
+	}
+
+	wf, err := LoadWorkflowFS(fsys, "main.yaml")
+	assert.NoError(t, err)
+
+	// Define test cases.
+	tests := []struct {
+		name        string        // test case name
+		phase       string        // the input
+		wantDep     []string      // expected dependencies
+		wantParam   []string      // expected params
+		wantTimeout time.Duration // expected timeout
+	}{
+		{name: "Case 1: outer phase", phase: "checkSsh"},
+		{name: "Case 2: group dependency is replaced by the leaf phases", phase: "kubeInit", wantDep: []string{"prep.b", "prep.c"}, wantParam: []string{"outputs.prep.b.h1.token"}},
+		{name: "Case 3: root phase depends on the include dependencies", phase: "prep.a", wantDep: []string{"checkSsh"}, wantTimeout: 30 * time.Second},
+		{name: "Case 4: dependency and output reference are prefixed", phase: "prep.b", wantDep: []string{"prep.a"}, wantParam: []string{"outputs.prep.a.h1.url", "version"}, wantTimeout: 30 * time.Second},
+		{name: "Case 5: own timeout is kept", phase: "prep.c", wantDep: []string{"checkSsh"}, wantTimeout: 5 * time.Second},
+		{name: "Case 6: default prefix is the included workflow name", phase: "cni.install"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase, ok := wf.Phases[tt.phase]
+			if !assert.True(t, ok, "phase %q not found", tt.phase) {
+				return
+			}
+			assert.ElementsMatch(t, tt.wantDep, phase.Dependency)
+			assert.ElementsMatch(t, tt.wantParam, phase.Param)
+			assert.Equal(t, tt.wantTimeout, phase.Timeout)
+		})
+	}
+	assert.Len(t, wf.Phases, 6)
+	assert.Len(t, wf.includeMap, 2)
+}
+
+// Name: TestExpandIncludeError
+func TestExpandIncludeError(t *testing.T) {
+	sub := &fstest.MapFile{Data: []byte("name: sub\nphases:\n  a:\n    fn: work\n")}
+
+	// Define test cases.
+	tests := []struct {
+		name    string // test case name
+		main    string // the input : the including workflow (sub.yaml is available)
+		wantErr string // expected error message part
+	}{
+		{name: "Case 1: missing file", main: "include:\n  - file: missing.yaml\n", wantErr: `include "missing.yaml"`},
+		{name: "Case 2: empty file", main: "include:\n  - prefix: x\n", wantErr: "file is empty"},
+		{name: "Case 3: prefix collides with a phase", main: "include:\n  - file: sub.yaml\nphases:\n  sub:\n    fn: work\n", wantErr: `prefix "sub" collides with phase "sub"`},
+		{name: "Case 4: prefix used twice", main: "include:\n  - file: sub.yaml\n  - file: sub.yaml\n", wantErr: `prefix "sub" is already used`},
+		{name: "Case 5: phase collides", main: "include:\n  - file: sub.yaml\n    prefix: p\nphases:\n  p.a:\n    fn: work\n", wantErr: `phase "p.a" collides`},
+		{name: "Case 6: include cycle", main: "include:\n  - file: main.yaml\n    prefix: self\n", wantErr: "cycle: main.yaml -> main.yaml"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"main.yaml": {Data: []byte("name: main\n" + tt.main)}, "sub.yaml": sub}
+			_, err := LoadWorkflowFS(fsys, "main.yaml")
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package phase2
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/abtransitionit/gocore/mock/yamlx"
+)
+
+// Description: parses a workflow from a YAML content (includes are not expanded)
+func parseWorkflow(data []byte) (*Workflow, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("loading workflow: the YAML content is empty")
+	}
+	workflow, err := yamlx.LoadYamlFileEmbed[Workflow](data)
+	if err != nil {
+		return nil, fmt.Errorf("loading workflow: %w", err)
+	}
+	workflow.source = data // keep it for validation
+	return workflow, nil
+}
+
+// Description: loads a workflow from a YAML content
+//
+// Notes:
+// - the content is kept: Workflow.Validate uses it to detect unknown keys
+// - included files are relative to the working dir
+func LoadWorkflowBytes(data []byte) (*Workflow, error) {
+	workflow, err := parseWorkflow(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := workflow.expandInclude("", getOsIncludeReader(), nil); err != nil {
+		return nil, fmt.Errorf("expanding includes: %w", err)
+	}
+	return workflow, nil
+}
+
+// Description: loads a workflow from a YAML file
+//
+// Parameters:
+// - filePath: the path of the file (absolute or relative to the working dir)
+//
+// Notes:
+// - included files are relative to the folder of the file
+func LoadWorkflowFile(filePath string) (*Workflow, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading workflow yaml file %s: %w", filePath, err)
+	}
+	workflow, err := parseWorkflow(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if err := workflow.expandInclude(filePath, getOsIncludeReader(), []string{filepath.Clean(filePath)}); err != nil {
+		return nil, fmt.Errorf("%s: expanding includes: %w", filePath, err)
+	}
+	return workflow, nil
+}
+
+// Description: loads a workflow from a YAML file of a file system
+//
+// Parameters:
+// - fsys: the file system (eg. an embed.FS, os.DirFS)
+// - fileName: the path of the file in the file system (eg. "phase.yaml")
+//
+// Notes:
+// - included files are read from the same file system (relative to the folder of the file)
+//
+// Example Usage:
+//
+//	//go:embed phase.yaml
+//	var wkfFS embed.FS
+//	workflow, err := phase2.LoadWorkflowFS(wkfFS, "phase.yaml")
+func LoadWorkflowFS(fsys fs.FS, fileName string) (*Workflow, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("loading workflow %s: file system is nil", fileName)
+	}
+	data, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("reading workflow yaml file %s: %w", fileName, err)
+	}
+	workflow, err := parseWorkflow(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	if err := workflow.expandInclude(fileName, getFsIncludeReader(fsys), []string{path.Clean(fileName)}); err != nil {
+		return nil, fmt.Errorf("%s: expanding includes: %w", fileName, err)
+	}
+	return workflow, nil
+}
+
+// Description: returns the default folder that contains the user workflow files
+//
+// Notes:
+// - $GOLUC_WORKFLOW if set else ~/wkspc/.config/goluc/workflow (the folder of the user config, see viperx)
+func GetDefaultWorkflowDir() (string, error) {
+	if dir := os.Getenv("GOLUC_WORKFLOW"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "wkspc", ".config", "goluc", "workflow"), nil
+}
+
+// Description: loads a workflow from the first source that overrides the others
+//
+// Parameters:
+// - fsys: the file system that contains the default workflow (eg. an embed.FS) - can be nil
+// - fileName: the name of the workflow file (eg. "phase.yaml")
+//
+// Notes:
+// - Sources are checked in the following order. The last one found wins (a workflow is not merged):
+// - 1 - Embedded default (fileName in fsys)
+// - 2 - User config ($GOLUC_WORKFLOW/fileName if set else ~/wkspc/.config/goluc/workflow/fileName)
+// - 3 - Local file (aka. current working dir ./fileName)
+// - unlike GetWorkflow, it does not need the source tree: the binary can be installed on another machine
+func LoadWorkflow(fsys fs.FS, fileName string, logger logx.Logger) (*Workflow, error) {
+
+	// 1 - define the sources
+	type source struct {
+		name string
+		path string
+		load func() (*Workflow, error)
+	}
+	var sourceList []source
+
+	// 11 - embedded default
+	if fsys != nil {
+		sourceList = append(sourceList, source{name: "embedded", path: fileName, load: func() (*Workflow, error) {
+			return LoadWorkflowFS(fsys, fileName)
+		}})
+	}
+
+	// 12 - user config
+	userDir, err := GetDefaultWorkflowDir()
+	if err != nil {
+		return nil, err
+	}
+	userPath := filepath.Join(userDir, fileName)
+	sourceList = append(sourceList, source{name: "user", path: userPath, load: func() (*Workflow, error) {
+		return LoadWorkflowFile(userPath)
+	}})
+
+	// 13 - working dir
+	sourceList = append(sourceList, source{name: "local", path: fileName, load: func() (*Workflow, error) {
+		return LoadWorkflowFile(fileName)
+	}})
+
+	// 2 - load the last source found
+	for i := len(sourceList) - 1; i >= 0; i-- {
+		workflow, err := sourceList[i].load()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading %s workflow: %w", sourceList[i].name, err)
+		}
+		logger.Debugf("found workflow file (%s): %s", sourceList[i].name, sourceList[i].path)
+		return workflow, nil
+	}
+
+	// 3 - handle not found
+	return nil, fmt.Errorf("workflow file %q not found (checked embedded, %q and working dir): %w", fileName, userPath, fs.ErrNotExist)
+}
@@ -0,0 +1,98 @@
+package phase2
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+// Description: returns the YAML of a workflow named name
+func getTestWorkflowYaml(name string) []byte {
+	return []byte("name: " + name + "\nphases:\n  a:\n    fn: work\n    node: nodes\n")
+}
+
+// Name: TestLoadWorkflow
+func TestLoadWorkflow(t *testing.T) {
+	// create inputs for the test : an embedded default
+	logger := logx.GetLogger()
+	embedded := fstest.MapFS{"phase.yaml": {Data: getTestWorkflowYaml("embedded")}}
+
+	// Define test cases.
+	tests := []struct {
+		name     string // test case name
+		fsys     fs.FS  // the input : the embedded default
+		userYaml []byte // the input : the user config file (nil if none)
+		local    []byte // the input : the working dir file (nil if none)
+		want     string // expected workflow name
+		wantErr  bool   // expected error
+		wantIs   error  // expected wrapped error (nil if none)
+	}{
+		{name: "Case 1: embedded default", fsys: embedded, want: "embedded"},
+		{name: "Case 2: user config overrides the embedded default", fsys: embedded, userYaml: getTestWorkflowYaml("user"), want: "user"},
+		{name: "Case 3: working dir overrides the user config", fsys: embedded, userYaml: getTestWorkflowYaml("user"), local: getTestWorkflowYaml("local"), want: "local"},
+		{name: "Case 4: no embedded default", fsys: nil, local: getTestWorkflowYaml("local"), want: "local"},
+		{name: "Case 5: not found", fsys: fstest.MapFS{}, wantErr: true, wantIs: fs.ErrNotExist},
+		{name: "Case 6: an invalid override is an error", fsys: embedded, userYaml: []byte("name: [user"), wantErr: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userDir := t.TempDir()
+			localDir := t.TempDir()
+			t.Setenv("GOLUC_WORKFLOW", userDir)
+			t.Chdir(localDir)
+			if tt.userYaml != nil {
+				assert.NoError(t, os.WriteFile(filepath.Join(userDir, "phase.yaml"), tt.userYaml, 0o644))
+			}
+			if tt.local != nil {
+				assert.NoError(t, os.WriteFile(filepath.Join(localDir, "phase.yaml"), tt.local, 0o644))
+			}
+
+			workflow, err := LoadWorkflow(tt.fsys, "phase.yaml", logger)
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.wantIs != nil {
+					assert.ErrorIs(t, err, tt.wantIs)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, workflow.Name)
+			assert.Contains(t, workflow.Phases, "a")
+		})
+	}
+}
+
+// Name: TestLoadWorkflowBytes
+func TestLoadWorkflowBytes(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name    string // test case name
+		data    []byte // the input
+		want    string // expected workflow name
+		wantErr bool   // expected error
+	}{
+		{name: "Case 1: valid YAML", data: getTestWorkflowYaml("wkf"), want: "wkf"},
+		{name: "Case 2: empty content", data: nil, wantErr: true},
+		{name: "Case 3: invalid YAML", data: []byte("phases: [a"), wantErr: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow, err := LoadWorkflowBytes(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, workflow.Name)
+		})
+	}
+}
@@ -88,6 +88,9 @@ func (wf *Workflow) TopoSortByTier(logger logx.Logger) ([][]Phase, error) {
 			phase := wf.Phases[name]
 			phase.Name = name
 			phase.WkfName = wf.Name
+			if phase.Timeout == 0 {
+				phase.Timeout = wf.Timeout // inherit the workflow default
+			}
 			currentTier = append(currentTier, phase)
 
 			for _, neighbor := range graph[name] {
@@ -2,10 +2,7 @@ package phase2
 
 import (
 	"fmt"
-	"path"
 	"path/filepath"
-	"reflect"
-	"runtime"
 	"strings"
 
 	"github.com/abtransitionit/gocore/logx"
@@ -70,7 +67,7 @@ func (wf *Workflow) GetTierView(tierList [][]Phase, logger logx.Logger) (string,
 
 	// Table header (no Params column anymore)
 	// b.WriteString("Tier\tIdP\tPhase\tExe Node\tDescription\tDependencies\n")
-	b.WriteString("Tier\tIdP\tPhase\tTarget\tParam\n")
+	b.WriteString("Tier\tIdP\tPhase\tGroup\tTarget\tParam\tWhen\n")
 
 	// Iterate through tiers
 	for tierIndex, tierList := range tierList {
@@ -90,11 +87,30 @@ func (wf *Workflow) GetTierView(tierList [][]Phase, logger logx.Logger) (string,
 
 			param := "none"
 			if len(p.Param) > 0 { // assuming Param is a slice of strings
-				param = strings.Join(p.Param, ", ")
+				paramList := make([]string, len(p.Param))
+				for i, key := range p.Param {
+					paramList[i] = key
+					if p.item != nil && key == forEachParam { // the item of a forEach phase (not a config key that contains "item")
+						paramList[i] = fmt.Sprintf("%s=%v", forEachParam, p.item.value)
+					}
+				}
+				param = strings.Join(paramList, ", ")
 			}
 
-			b.WriteString(fmt.Sprintf("%d\t%d\t%s\t%s\t%s\n",
-				tierID, idp, p.Name, node, param))
+			group := p.group
+			if group == "" {
+				group = "none"
+			}
+
+			when := "none"
+			if p.skipReason != "" {
+				when = p.skipReason
+			} else if p.When != "" {
+				when = "run"
+			}
+
+			b.WriteString(fmt.Sprintf("%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+				tierID, idp, p.Name, group, node, param, when))
 		}
 
 		// b.WriteString(sep)
@@ -103,35 +119,20 @@ func (wf *Workflow) GetTierView(tierList [][]Phase, logger logx.Logger) (string,
 	return b.String(), nil
 }
 
+// Description: returns a view of the functions registered for a command (ie. a workflow)
 func (wf *Workflow) GetFunctionView(cmdPathName string, registry *FnRegistry) (string, error) {
 
 	cmdBase := filepath.Base(cmdPathName)
-	keys := registry.List(cmdBase)
-	if len(keys) == 0 {
+	infoList := registry.Describe(cmdBase)
+	if len(infoList) == 0 {
 		return "", nil
 	}
 
 	var b strings.Builder
-	b.WriteString("Key\tModule\tFunction\n")
-
-	for _, key := range keys {
-		fn, ok := registry.Get(cmdBase, key)
-		if !ok {
-			continue
-		}
-
-		fnVal := reflect.ValueOf(fn)
-		ptr := fnVal.Pointer()
-		rf := runtime.FuncForPC(ptr)
-
-		module, fnName := "<??>", "<??>"
-		if rf != nil {
-			fullName := strings.TrimPrefix(rf.Name(), "github.com/abtransitionit/")
-			module = path.Dir(fullName)
-			fnName = path.Base(fullName)
-		}
+	b.WriteString("Key\tModule\tFunction\tCtx\n")
 
-		fmt.Fprintf(&b, "%s\t%s\t%s\n", key, module, fnName)
+	for _, info := range infoList {
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%v\n", info.Alias, info.Module, info.Function, info.IsCtx)
 	}
 
 	return b.String(), nil
@@ -0,0 +1,124 @@
+package run
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Name: FakeExecutor
+//
+// Description: a scripted Executor for the tests - records the commands and never runs them.
+//
+// Notes:
+//
+// - a command gets the response of the first rule whose pattern it contains (an empty pattern matches any command).
+// - a command that matches no rule returns an empty output and no error.
+// - safe for concurrent use.
+//
+// Example Usage:
+//
+//	executor := run.NewFakeExecutor("o1u").On("helm repo list", "NAME\tURL", nil)
+//	output, err := helm.ListRepoWith(ctx, executor, logger)
+//	assert.Equal(t, []string{"helm repo list"}, executor.Commands())
+type FakeExecutor struct {
+	mu          sync.Mutex
+	target      string
+	ruleList    []fakeRule
+	commandList []string
+}
+
+// Description: the scripted response of a FakeExecutor
+type fakeRule struct {
+	pattern string
+	output  string
+	err     error
+}
+
+// Name: NewFakeExecutor
+//
+// Description: constructor that returns an instance of FakeExecutor.
+//
+// Inputs:
+//
+// - target: string: the value returned by Target (eg. "local", "o1u").
+func NewFakeExecutor(target string) *FakeExecutor {
+	return &FakeExecutor{target: target}
+}
+
+// Name: On
+//
+// Description: adds a rule: a command that contains pattern returns output and err.
+//
+// Return:
+//
+// - the executor (to chain the rules).
+func (fake *FakeExecutor) On(pattern, output string, err error) *FakeExecutor {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.ruleList = append(fake.ruleList, fakeRule{pattern: pattern, output: output, err: err})
+	return fake
+}
+
+// Name: Commands
+//
+// Description: returns the commands received so far (in order).
+func (fake *FakeExecutor) Commands() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]string(nil), fake.commandList...)
+}
+
+// Description: records the command and returns its scripted output
+func (fake *FakeExecutor) Run(ctx context.Context, cli string) (string, error) {
+	result, err := fake.Exec(ctx, cli)
+	return result.Output, err
+}
+
+// Description: records the command and returns its scripted response
+//
+// Notes:
+// - the exit code is 0 without error, the exit code of the result carried by the error (see GetResult) or 1
+func (fake *FakeExecutor) Exec(ctx context.Context, cli string) (*CommandResult, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.commandList = append(fake.commandList, cli)
+	result := &CommandResult{Command: cli, Host: fake.target, StartTime: time.Now()}
+	if err := ctx.Err(); err != nil {
+		result.ExitCode = -1
+		return result, err
+	}
+	for _, rule := range fake.ruleList {
+		if strings.Contains(cli, rule.pattern) {
+			result.Stdout = rule.output
+			result.Output = strings.TrimSpace(rule.output)
+			if rule.err != nil {
+				result.ExitCode = 1
+				if scripted := GetResult(rule.err); scripted != nil {
+					result.ExitCode = scripted.ExitCode
+				}
+			}
+			return result, rule.err
+		}
+	}
+	return result, nil
+}
+
+// Description: records the command and writes its scripted output to stdout
+func (fake *FakeExecutor) RunStream(ctx context.Context, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+	result, err := fake.Exec(ctx, cli)
+	if result.Stdout != "" {
+		if _, writeErr := io.WriteString(stdout, result.Stdout+"\n"); writeErr != nil {
+			return result, writeErr
+		}
+	}
+	result.Stdout, result.Output = "", ""
+	return result, err
+}
+
+// Description: returns the target given to NewFakeExecutor
+func (fake *FakeExecutor) Target() string {
+	return fake.target
+}
@@ -0,0 +1,152 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/abtransitionit/gocore/errorx"
+	"github.com/abtransitionit/gocore/logx"
+)
+
+// Name: Executor
+//
+// Description: runs CLIs on a target (the local machine, a remote host, a fake for the tests).
+//
+// Notes:
+//
+// - Run returns the combined standard output and standard error (trimmed).
+// - Exec returns the structured result (exit code, stdout, stderr, duration) - see CommandResult.
+// - RunStream writes the output to the writers as it is produced.
+// - the errors are typed: *ConnectionError, *CommandNotFoundError, *ExitError, *TimeoutError (or the ctx error when cancelled).
+// - when the ctx is done (cancel, deadline), the command is killed.
+// - Target identifies the target in the logs: "local" or the remote host.
+type Executor interface {
+	Run(ctx context.Context, cli string) (string, error)
+	Exec(ctx context.Context, cli string) (*CommandResult, error)
+	RunStream(ctx context.Context, cli string, stdout, stderr io.Writer) (*CommandResult, error)
+	Target() string
+}
+
+// Name: GetExecutor
+//
+// Description: returns the executor of a target.
+//
+// Inputs:
+//
+// - isLocal: bool: Whether to run the commands locally or remotely.
+// - remoteHost: string: The alias of the remote host if running remotely.
+//
+// Notes:
+//
+// - the remote executor runs the ssh CLI (see ExecCliSsh): the whole ssh config applies (ProxyJump, ControlMaster, Match, ...).
+// - to reuse one native connection per host, build the executor explicitly: NewSshExecutor(...).ForHost(remoteHost).
+func GetExecutor(isLocal bool, remoteHost string) Executor {
+	if isLocal {
+		return LocalExecutor{}
+	}
+	return &sshCliExecutor{host: remoteHost}
+}
+
+// Name: LocalExecutor
+//
+// Description: runs CLIs on the local machine with `sh -c`.
+type LocalExecutor struct{}
+
+// Description: runs a CLI locally - see RunCliLocalContext
+func (LocalExecutor) Run(ctx context.Context, cli string) (string, error) {
+	return RunCliLocalContext(ctx, cli)
+}
+
+// Description: runs a CLI locally and returns its structured result - see ExecCliLocal
+func (LocalExecutor) Exec(ctx context.Context, cli string) (*CommandResult, error) {
+	return ExecCliLocal(ctx, cli)
+}
+
+// Description: runs a CLI locally and streams its output
+func (LocalExecutor) RunStream(ctx context.Context, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+	result, err := runLocal(ctx, cli, stdout, stderr, false)
+	if err != nil {
+		return result, errorx.Wrap(err, "command failed: %s", cli)
+	}
+	return result, nil
+}
+
+// Description: returns "local"
+func (LocalExecutor) Target() string {
+	return "local"
+}
+
+// Description: runs CLIs on a host with the ssh CLI - see GetExecutor
+type sshCliExecutor struct {
+	host string
+}
+
+func (executor *sshCliExecutor) Run(ctx context.Context, cli string) (string, error) {
+	return RunCliSshContext(ctx, executor.host, cli)
+}
+
+func (executor *sshCliExecutor) Exec(ctx context.Context, cli string) (*CommandResult, error) {
+	return ExecCliSsh(ctx, executor.host, cli)
+}
+
+func (executor *sshCliExecutor) RunStream(ctx context.Context, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+	result, err := runCliSsh(ctx, executor.host, cli, "$SHELL -l", stdout, stderr)
+	if err != nil {
+		return result, errorx.Wrap(err, "failed to run remote command on '%s'", executor.host)
+	}
+	return result, nil
+}
+
+func (executor *sshCliExecutor) Target() string {
+	return executor.host
+}
+
+// Description: runs CLIs on a host over the connections of a SshExecutor
+type sshHostExecutor struct {
+	pool *SshExecutor
+	host string
+}
+
+func (executor *sshHostExecutor) Run(ctx context.Context, cli string) (string, error) {
+	return executor.pool.Run(ctx, executor.host, cli)
+}
+
+func (executor *sshHostExecutor) Exec(ctx context.Context, cli string) (*CommandResult, error) {
+	return executor.pool.Exec(ctx, executor.host, cli)
+}
+
+func (executor *sshHostExecutor) RunStream(ctx context.Context, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+	return executor.pool.RunStream(ctx, executor.host, cli, stdout, stderr)
+}
+
+func (executor *sshHostExecutor) Target() string {
+	return executor.host
+}
+
+// Name: ExecuteCliQueryWith
+//
+// Description: runs the provided command string (cli) with an executor - see ExecuteCliQuery.
+//
+// Inputs:
+// - executor: Executor: where the command runs (local, remote, fake).
+// - errorHandler: CustomErrorHandler: decides if an error is a "soft" error (the output is returned without error).
+func ExecuteCliQueryWith(ctx context.Context, executor Executor, cli string, logger logx.Logger, errorHandler CustomErrorHandler) (string, error) {
+
+	// 1. run the command
+	logger.Debugf("running on %s: %s", executor.Target(), cli)
+	output, err := executor.Run(ctx, cli)
+
+	// 2. Handle "errors" that are not true errors
+	if errorHandler(err, logger) {
+		return output, nil
+	}
+
+	// 3. Handle true execution errors
+	if err != nil {
+		// Return a wrapped error that includes the command run
+		return output, fmt.Errorf("failed to run command: %s: %w", cli, err)
+	}
+
+	return output, nil
+}
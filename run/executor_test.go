@@ -0,0 +1,96 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/abtransitionit/gocore/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestExecuteCliQueryWith
+func TestExecuteCliQueryWith(t *testing.T) {
+	// create inputs for the test : a scripted executor and a handler of soft errors
+	logger := logx.GetLogger()
+	executor := NewFakeExecutor("o1u").
+		On("helm repo list", "Error: no repositories to show", errors.New("no repositories to show")).
+		On("kubectl get node", "node1 Ready", nil).
+		On("false", "ko", errors.New("exit status 1"))
+	softHandler := func(err error, logger logx.Logger) bool {
+		return err != nil && strings.Contains(err.Error(), "no repositories")
+	}
+
+	// Define test cases.
+	tests := []struct {
+		name    string // test case name
+		cli     string // the input
+		want    string // expected output
+		wantErr bool   // expected error
+	}{
+		{name: "Case 1: scripted output", cli: "kubectl get node -o wide", want: "node1 Ready"},
+		{name: "Case 2: soft error", cli: "helm repo list", want: "Error: no repositories to show"},
+		{name: "Case 3: true error", cli: "false", want: "ko", wantErr: true},
+		{name: "Case 4: no rule", cli: "uptime", want: ""},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExecuteCliQueryWith(context.Background(), executor, tt.cli, logger, softHandler)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	assert.Equal(t, []string{"kubectl get node -o wide", "helm repo list", "false", "uptime"}, executor.Commands())
+}
+
+// Name: TestLocalExecutor
+func TestLocalExecutor(t *testing.T) {
+	executor := LocalExecutor{}
+	assert.Equal(t, "local", executor.Target())
+
+	// Run: the combined output is trimmed
+	got, err := executor.Run(context.Background(), "echo out; sleep 0.1; echo err >&2")
+	assert.NoError(t, err)
+	assert.Equal(t, "out\nerr", got)
+
+	// RunStream: the outputs are written to the writers
+	var stdout, stderr bytes.Buffer
+	result, err := executor.RunStream(context.Background(), "echo out; echo err >&2; exit 2", &stdout, &stderr)
+	assert.Error(t, err)
+	assert.Equal(t, 2, result.ExitCode)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+// Name: TestGetExecutor
+func TestGetExecutor(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name       string // test case name
+		isLocal    bool   // the input
+		remoteHost string // the input
+		want       string // expected target
+	}{
+		{name: "Case 1: local", isLocal: true, want: "local"},
+		{name: "Case 2: remote host", remoteHost: "o1u", want: "o1u"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := GetExecutor(tt.isLocal, tt.remoteHost)
+			assert.Equal(t, tt.want, executor.Target())
+			// the remote executor runs the ssh CLI - the native client is opt-in
+			_, isNative := executor.(*sshHostExecutor)
+			assert.False(t, isNative)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package run
+
+import "os/exec"
+
+// Description: a no-op on the platforms without process groups
+//
+// Notes:
+// - only the process started is killed when the ctx is done (the default behavior of exec.CommandContext)
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package run
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Description: runs a command in its own process group - the whole group is killed when the ctx is done
+//
+// Notes:
+// - `sh -c` forks the commands of a pipeline: killing the shell alone leaves them running (and holding the output pipe)
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// a negative pid targets the process group
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
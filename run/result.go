@@ -0,0 +1,228 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Description: the exit code of a shell when the command is not found
+const exitCodeNotFound = 127
+
+// Description: the exit code of the ssh CLI when the connection fails
+const exitCodeSshConnection = 255
+
+// Name: CommandResult
+//
+// Description: the outcome of a command.
+//
+// Notes:
+//
+// - returned by the Exec helpers (ExecCliLocal, ExecCliSsh, Executor.Exec) and carried by the typed errors (ExitError, CommandNotFoundError, TimeoutError).
+// - Stdout and Stderr are empty when the output is streamed (RunStream).
+// - the order of the lines of Output is the order they were read: a line of stdout and a line of stderr written at the same time may be swapped.
+type CommandResult struct {
+	Command   string        // the command as given by the caller
+	Host      string        // "local" or the remote host
+	ExitCode  int           // -1 if the command did not exit (not started, killed by a signal, connection lost)
+	Stdout    string        // not trimmed
+	Stderr    string        // not trimmed
+	Output    string        // stdout and stderr in the order they were produced (trimmed) - what the string helpers return
+	StartTime time.Time     //
+	Duration  time.Duration //
+	Signal    string        // the signal that killed the command (as reported by the OS or the SSH server)
+}
+
+// Description: reports whether the command exited with code 0
+func (result *CommandResult) Success() bool {
+	return result != nil && result.ExitCode == 0
+}
+
+// Description: returns the combined output (safe on a nil result)
+func (result *CommandResult) getOutput() string {
+	if result == nil {
+		return ""
+	}
+	return result.Output
+}
+
+// Name: ExitError
+//
+// Description: the command ran and exited with a non-zero code (or was killed by a signal).
+type ExitError struct {
+	Result *CommandResult
+}
+
+func (e *ExitError) Error() string {
+	if e.Result.Signal != "" {
+		return fmt.Sprintf("killed by signal %s", e.Result.Signal)
+	}
+	return fmt.Sprintf("exit code %d", e.Result.ExitCode)
+}
+
+// Name: CommandNotFoundError
+//
+// Description: the command (or a program it calls) is not found on the host.
+//
+// Notes:
+//
+// - detected from the exit code 127 of the shell or from a program that cannot be started.
+type CommandNotFoundError struct {
+	Result *CommandResult
+	Err    error // the start error (nil when detected from the exit code)
+}
+
+func (e *CommandNotFoundError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("command not found: %v", e.Err)
+	}
+	return fmt.Sprintf("command not found on '%s' (exit code %d)", e.Result.Host, e.Result.ExitCode)
+}
+
+func (e *CommandNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// Name: TimeoutError
+//
+// Description: the command was killed because the deadline of the ctx was exceeded.
+//
+// Notes:
+//
+// - distinct from a command failure: the command did not get the time to complete.
+// - errors.Is(err, context.DeadlineExceeded) is true.
+type TimeoutError struct {
+	Result *CommandResult
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command timed out on '%s' after %s", e.Result.Host, e.Result.Duration.Round(time.Millisecond))
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Name: ConnectionError
+//
+// Description: the remote host cannot be reached, the authentication failed or the connection was lost.
+type ConnectionError struct {
+	Host string
+	Err  error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("connection to '%s' failed: %v", e.Host, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// Description: returns the typed error of a command that exited with a non-zero code
+//
+// Return:
+// - nil if the command succeeded
+func getExitError(result *CommandResult) error {
+	switch {
+	case result.ExitCode == 0:
+		return nil
+	case result.ExitCode == exitCodeNotFound:
+		return &CommandNotFoundError{Result: result}
+	default:
+		return &ExitError{Result: result}
+	}
+}
+
+// Description: returns the error of a command killed because the ctx is done
+//
+// Return:
+// - a *TimeoutError if the deadline was exceeded, the ctx error otherwise (eg. context.Canceled)
+func getCtxError(ctx context.Context, result *CommandResult) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Result: result}
+	}
+	return ctx.Err()
+}
+
+// Description: returns the result carried by an error (ExitError, CommandNotFoundError, TimeoutError)
+//
+// Notes:
+// - nil if the error carries no result (eg. a ConnectionError)
+func GetResult(err error) *CommandResult {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Result
+	}
+	var notFoundErr *CommandNotFoundError
+	if errors.As(err, &notFoundErr) {
+		return notFoundErr.Result
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Result
+	}
+	return nil
+}
+
+// Description: captures the output of a command: stdout, stderr and both in the order they are produced
+//
+// Notes:
+// - the writes are serialized: stdout and stderr are copied by 2 goroutines
+type outputCapture struct {
+	mu       sync.Mutex
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	combined bytes.Buffer
+}
+
+// Description: returns the writers of the standard output and the standard error
+func (capture *outputCapture) getWriters() (io.Writer, io.Writer) {
+	return &lockedWriter{mu: &capture.mu, w: io.MultiWriter(&capture.stdout, &capture.combined)},
+		&lockedWriter{mu: &capture.mu, w: io.MultiWriter(&capture.stderr, &capture.combined)}
+}
+
+// Description: sets the output of a result
+func (capture *outputCapture) setResult(result *CommandResult) {
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	result.Stdout = capture.stdout.String()
+	result.Stderr = capture.stderr.String()
+	result.Output = strings.TrimSpace(capture.combined.String())
+}
+
+// Description: a writer that shares a lock with other writers
+//
+// Notes:
+// - the writes to stdout and stderr are serialized (they may be the same writer)
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
+// Description: sets the exit code and the signal of a result from the state of an exited process
+func setProcessState(result *CommandResult, state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+	result.ExitCode = state.ExitCode()
+	if status, ok := state.Sys().(interface {
+		Signaled() bool
+		Signal() syscall.Signal
+	}); ok && status.Signaled() {
+		result.Signal = status.Signal().String()
+	}
+}
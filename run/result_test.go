@@ -0,0 +1,55 @@
+package run
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestExecCliLocal
+func TestExecCliLocal(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name         string // test case name
+		cli          string // the input
+		wantExitCode int    // expected exit code
+		wantStdout   string // expected standard output
+		wantStderr   string // expected standard error
+		wantOutput   string // expected combined output
+		wantErrType  string // expected type of error ("" for no error)
+	}{
+		{name: "Case 1: success", cli: "echo out", wantStdout: "out\n", wantOutput: "out"},
+		{name: "Case 2: stdout and stderr are separated", cli: "echo out; sleep 0.1; echo err >&2", wantStdout: "out\n", wantStderr: "err\n", wantOutput: "out\nerr"},
+		{name: "Case 3: non-zero exit code", cli: "echo ko; exit 3", wantExitCode: 3, wantStdout: "ko\n", wantOutput: "ko", wantErrType: "exit"},
+		{name: "Case 4: command not found", cli: "nosuchcommand-xyz 2>/dev/null", wantExitCode: 127, wantErrType: "notfound"},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExecCliLocal(context.Background(), tt.cli)
+			assert.Equal(t, tt.cli, result.Command)
+			assert.Equal(t, "local", result.Host)
+			assert.Equal(t, tt.wantExitCode, result.ExitCode)
+			assert.Equal(t, tt.wantStdout, result.Stdout)
+			assert.Equal(t, tt.wantStderr, result.Stderr)
+			assert.Equal(t, tt.wantOutput, result.Output)
+			assert.False(t, result.StartTime.IsZero())
+
+			switch tt.wantErrType {
+			case "":
+				assert.NoError(t, err)
+				assert.True(t, result.Success())
+			case "exit":
+				var exitErr *ExitError
+				assert.ErrorAs(t, err, &exitErr)
+				assert.Same(t, result, GetResult(err))
+			case "notfound":
+				var notFoundErr *CommandNotFoundError
+				assert.ErrorAs(t, err, &notFoundErr)
+				assert.Same(t, result, GetResult(err))
+			}
+		})
+	}
+}
@@ -3,44 +3,59 @@ package run
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"time"
 
 	"github.com/abtransitionit/gocore/errorx"
 	"github.com/abtransitionit/gocore/logx"
 )
 
+// Description: the time to wait for the I/O of a killed process to complete
+const waitDelay = 5 * time.Second
+
+// Name: RunCliSshLive
+//
+// Description: Executes a command on a remote VM via SSH and streams its output to the standard output and error.
+//
+// Notes:
+//
+// - the output is written as is - use ExecStreamTo with NewHostWriter to prefix each line with the VM name, or ExecStream to get the lines with a callback.
+// - no Exec counterpart: ExecStreamTo(ctx, GetExecutor(false, vmName), cli, os.Stdout, os.Stderr) streams the output and returns the *CommandResult.
 func RunCliSshLive(vmName, cli string) error {
+	return RunCliSshLiveContext(context.Background(), vmName, cli)
+}
+
+// Name: RunCliSshLiveContext
+//
+// Description: Executes a command on a remote VM via SSH and streams its output - context-aware variant of RunCliSshLive.
+//
+// Notes:
+//
+// - when the ctx is done (cancel, deadline), the local ssh client is killed and the remote command is terminated (see getRemoteCommand).
+// - the error is a *TimeoutError when the deadline is exceeded.
+func RunCliSshLiveContext(ctx context.Context, vmName, cli string) error {
 	// step: check the VM is reachable
 	isSshReachable, err := IsVmSshReachable(vmName)
 	if err != nil {
 		return errorx.Wrap(err, "failed to check VM SSH reachability")
 	}
 	if !isSshReachable {
-		return errorx.New("vm '%s' is not SSH reachable", vmName)
+		return errorx.Wrap(&ConnectionError{Host: vmName, Err: errors.New("not configured or not reachable")}, "vm '%s' is not SSH reachable", vmName)
 	}
 
-	// step: Base64 encode the input to handle complex quoting and special characters.
-	cliEncoded := base64.StdEncoding.EncodeToString([]byte(cli))
-
 	// step: Define the full SSH command to run (same as RunCliSsh).
-	command := fmt.Sprintf(
-		`ssh -o BatchMode=yes -o ConnectTimeout=5 %s "echo '%s' | base64 --decode | sh"`,
-		vmName,
-		cliEncoded,
-	)
-
-	// step: Use exec.Command so we can stream live output.
-	cmd := exec.Command("bash", "-c", command)
-	cmd.Stdout = os.Stdout // stream remote stdout directly
-	cmd.Stderr = os.Stderr // stream remote stderr directly
+	command := fmt.Sprintf(`ssh -o BatchMode=yes -o ConnectTimeout=5 %s '%s'`, vmName, getRemoteCommand(cli, "sh"))
 
 	// step: Run and stream live output
-	if err := cmd.Run(); err != nil {
+	result, err := runLocal(ctx, command, os.Stdout, os.Stderr, true)
+	result.Command = cli
+	result.Host = vmName
+	if err != nil {
 		return errorx.Wrap(err, "failed to run remote command on '%s'", vmName)
 	}
 
@@ -69,35 +84,8 @@ func RunCliSshLive(vmName, cli string) error {
 // - The `-o ConnectTimeout=5` flag sets a 5-second timeout, so the function never hang indefinitely. Without this, if the remote VM is powered off or unreachable, the SSH command could hang for several minutes before timing out
 // - The remote command is Base64 encoded to avoid issues with complex quotes and special characters.
 func RunCliSsh(vmName, cli string) (string, error) {
-
-	// step: check the VM is reachable
-	isSshReachable, err := IsVmSshReachable(vmName)
-	if err != nil {
-		// handle generic error explicitly: unexpected failure
-		return "", errorx.Wrap(err, "failed to check VM SSH reachability")
-	}
-	if !isSshReachable {
-		// handle specific error explicitly: expected outcome
-		return "", errorx.New("vm '%s' is not SSH reachable", vmName)
-	}
-
-	// step: Base64 encode the input to handle complex quoting and special characters.
-	cliEncoded := base64.StdEncoding.EncodeToString([]byte(cli))
-
-	// step: Now that the VM is reachable, define the full SSH command to run.
-	command := fmt.Sprintf(`ssh -o BatchMode=yes -o ConnectTimeout=5 %s "echo '%s' | base64 --decode | $SHELL -l"`, vmName, cliEncoded)
-
-	// step: Run the command.
-	output, err := RunCliLocal(command)
-
-	// manage error
-	if err != nil {
-		// handle generic error explicitly: unexpected failure
-		return output, errorx.Wrap(err, "failed to run remote command on '%s'", vmName)
-	}
-
-	// success
-	return output, nil
+	result, err := ExecCliSsh(context.Background(), vmName, cli)
+	return result.getOutput(), err
 }
 
 // Name: RunCLILocal
@@ -115,35 +103,222 @@ func RunCliSsh(vmName, cli string) (string, error) {
 // - Captures both standard output and standard error.
 // - Trims leading/trailing whitespace from the final output.
 func RunCliLocal(command string) (string, error) {
-	cmd := exec.Command("sh", "-c", command)
+	result, err := ExecCliLocal(context.Background(), command)
+	return result.getOutput(), err
+}
+
+// Name: RunCliLocalContext
+//
+// Description: Executes a local command or complex CLI pipeline - context-aware variant of RunCliLocal.
+//
+// Inputs:
+//
+// - ctx: context.Context: when the ctx is done (cancel, deadline), the process group is killed (the shell and the commands it forked).
+// - command: string: The complete command string to be executed.
+//
+// Return:
+//
+// - string: The combined standard output and standard error from the command.
+// - error: An error if the command fails, exits with a non-zero status or is killed because the ctx is done.
+//
+// Notes:
+//
+// - once the process is killed, Wait returns after waitDelay even if a grandchild still holds the output pipe.
+func RunCliLocalContext(ctx context.Context, command string) (string, error) {
+	result, err := ExecCliLocal(ctx, command)
+	return result.getOutput(), err
+}
+
+// Name: RunCliSshContext
+//
+// Description: Executes a command on a remote VM via SSH - context-aware variant of RunCliSsh.
+//
+// Notes:
+//
+// - when the ctx is done (cancel, deadline), the local ssh process is killed.
+func RunCliSshContext(ctx context.Context, vmName, cli string) (string, error) {
+	result, err := ExecCliSsh(ctx, vmName, cli)
+	return result.getOutput(), err
+}
+
+// Name: ExecCliLocal
+//
+// Description: Executes a local command or complex CLI pipeline and returns its structured result.
+//
+// Inputs:
+//
+// - ctx: context.Context: when the ctx is done (cancel, deadline), the process group is killed (the shell and the commands it forked).
+// - command: string: The complete command string to be executed.
+//
+// Return:
+//
+// - *CommandResult: exit code, stdout, stderr, duration, signal - also set when the command fails.
+// - error: a *CommandNotFoundError (exit code 127), a *ExitError (non-zero exit code), a *TimeoutError (deadline exceeded) or the ctx error (cancelled).
+//
+// Notes:
+//
+// - Uses `sh -c` to ensure complex commands with pipes and redirects execute correctly.
+// - once the process is killed, Wait returns after waitDelay even if a grandchild still holds the output pipe.
+func ExecCliLocal(ctx context.Context, command string) (*CommandResult, error) {
+	var capture outputCapture
+	stdout, stderr := capture.getWriters()
+	result, err := runLocal(ctx, command, stdout, stderr, false)
+	capture.setResult(result)
+
+	// manage error
+	if err != nil {
+		// handle generic error explicitly: the typed error and the output
+		return result, errorx.Wrap(err, "command failed: %s", result.Output)
+	}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+	// success
+	return result, nil
+}
 
-	// Run the command and wait for it to finish: This will return a non-nil error if the command exits with a non-zero status.
+// Description: runs a command with `sh -c` in its own process group and writes its output to the writers
+//
+// Inputs:
+// - keepStdin: bool: the standard input of the command stays open until the command exits (it is /dev/null otherwise) - see getRemoteCommand
+//
+// Return:
+// - the result: the output is not set (it is written to the writers)
+// - a typed error: *CommandNotFoundError, *ExitError, *TimeoutError or the ctx error
+//
+// Notes:
+// - when the ctx is done, the process group is killed (the shell and the commands it forked)
+// - once the process group is killed, Wait returns after waitDelay even if a process still holds the output pipe.
+func runLocal(ctx context.Context, command string, stdout, stderr io.Writer, keepStdin bool) (*CommandResult, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	result := &CommandResult{Command: command, Host: "local", ExitCode: -1, StartTime: time.Now()}
+
+	// step: define the standard input
+	if keepStdin {
+		stdinReader, stdinWriter, err := os.Pipe()
+		if err != nil {
+			return result, err
+		}
+		defer stdinWriter.Close() // EOF for the command - once it exited
+		defer stdinReader.Close()
+		cmd.Stdin = stdinReader
+	}
+
+	// step: Run the command and wait for it to finish
 	err := cmd.Run()
-	output := strings.TrimSpace(out.String())
+	result.Duration = time.Since(result.StartTime)
+	setProcessState(result, cmd.ProcessState)
+
+	// manage error
+	switch {
+	case ctx.Err() != nil:
+		// handle specific error explicitly: the process group was killed
+		return result, getCtxError(ctx, result)
+	case cmd.ProcessState == nil && err != nil:
+		// handle specific error explicitly: the shell cannot be started
+		return result, &CommandNotFoundError{Result: result, Err: err}
+	case getExitError(result) != nil:
+		// handle specific error explicitly: non-zero exit code
+		return result, getExitError(result)
+	default:
+		// eg. an I/O error
+		return result, err
+	}
+}
+
+// Name: ExecCliSsh
+//
+// Description: Executes a command on a remote VM via the ssh CLI and returns its structured result - see RunCliSsh.
+//
+// Return:
+//
+// - *CommandResult: the Command is cli and the Host is vmName.
+// - error: a *ConnectionError if the VM is not reachable (or ssh exits with 255), a *CommandNotFoundError, a *ExitError, a *TimeoutError or the ctx error.
+func ExecCliSsh(ctx context.Context, vmName, cli string) (*CommandResult, error) {
+	var capture outputCapture
+	stdout, stderr := capture.getWriters()
+	result, err := runCliSsh(ctx, vmName, cli, "$SHELL -l", stdout, stderr)
+	capture.setResult(result)
 
 	// manage error
 	if err != nil {
-		// handle generic error explicitly: unexpected failure
-		return output, errorx.Wrap(err, "command failed: %s", output)
+		// handle generic error explicitly: the typed error and the output
+		return result, errorx.Wrap(err, "failed to run remote command on '%s': %s", vmName, result.Output)
 	}
 
 	// success
-	return output, nil
+	return result, nil
+}
+
+// Description: runs a command on a remote VM with the ssh CLI and writes its output to the writers
+//
+// Inputs:
+// - shell: string: the remote shell that runs the CLI - see getRemoteCommand
+//
+// Return:
+// - the result: the output is not set (it is written to the writers) - the Command is cli and the Host is vmName
+// - a typed error: *ConnectionError, *CommandNotFoundError, *ExitError, *TimeoutError or the ctx error
+//
+// Notes:
+// - The `-o BatchMode=yes` flag prevents interactive prompts and long waits.
+// - The `-o ConnectTimeout=5` flag bounds the connection (the ctx bounds the whole command).
+func runCliSsh(ctx context.Context, vmName, cli, shell string, stdout, stderr io.Writer) (*CommandResult, error) {
+	result := &CommandResult{Command: cli, Host: vmName, ExitCode: -1, StartTime: time.Now()}
+
+	// step: check the VM is reachable
+	isSshReachable, err := IsVmSshReachable(vmName)
+	if err != nil {
+		// handle generic error explicitly: unexpected failure
+		return result, errorx.Wrap(err, "failed to check VM SSH reachability")
+	}
+	if !isSshReachable {
+		// handle specific error explicitly: expected outcome
+		return result, &ConnectionError{Host: vmName, Err: errors.New("not configured or not reachable")}
+	}
+
+	// step: Now that the VM is reachable, define the full SSH command to run - the remote command is terminated with the ssh client.
+	command := fmt.Sprintf(`ssh -o BatchMode=yes -o ConnectTimeout=5 %s '%s'`, vmName, getRemoteCommand(cli, shell))
+
+	// step: Run the command - the ssh client is killed when the ctx is done
+	result, err = runLocal(ctx, command, stdout, stderr, true)
+	result.Command = cli
+	result.Host = vmName
+
+	// manage error
+	if err != nil && result.ExitCode == exitCodeSshConnection {
+		// handle specific error explicitly: ssh exits with 255 when the connection fails
+		return result, &ConnectionError{Host: vmName, Err: err}
+	}
+	return result, err
 }
 
 // RunOnVm executes a CLI command on a remote VM via SSH
 func RunOnVm(vmName, cli string) (string, error) {
-	cmd := exec.Command("ssh", vmName, cli)
-	output, err := cmd.CombinedOutput()
+	return RunOnVmContext(context.Background(), vmName, cli)
+}
+
+// Name: RunOnVmContext
+//
+// Description: executes a CLI command on a remote VM via SSH - context-aware variant of RunOnVm.
+//
+// Notes:
+//
+// - when the ctx is done (cancel, deadline), the local ssh client is killed and the remote command is terminated (see getRemoteCommand).
+// - the error is a *TimeoutError when the deadline is exceeded.
+// - the output is the raw combined output (not trimmed).
+// - no Exec counterpart: ExecCliSsh returns the *CommandResult of a remote command (it also checks the reachability and never prompts).
+func RunOnVmContext(ctx context.Context, vmName, cli string) (string, error) {
+	command := fmt.Sprintf(`ssh %s '%s'`, vmName, getRemoteCommand(cli, "$SHELL"))
+
+	var output bytes.Buffer // stdout and stderr - like CombinedOutput
+	_, err := runLocal(ctx, command, &output, &output, true)
 	if err != nil {
 		// This error message now includes the output, which is useful for debugging.
-		return "", fmt.Errorf("failed to run command on VM %s: %w, output: %s", vmName, err, string(output))
+		return "", fmt.Errorf("failed to run command on VM %s: %w, output: %s", vmName, err, output.String())
 	}
-	return string(output), nil
+	return output.String(), nil
 }
 
 // func RunOnVm(vmName, cli string) error {
@@ -158,12 +333,25 @@ func RunOnVm(vmName, cli string) (string, error) {
 // RunOnLocal executes a CLI command on the local machine and returns the output.
 // It is an analogy to RunOnVm, as it captures and returns all output for consistent error reporting.
 func RunOnLocal(cli string) (string, error) {
-	cmd := exec.Command("sh", "-c", cli)
-	output, err := cmd.CombinedOutput()
+	return RunOnLocalContext(context.Background(), cli)
+}
+
+// Name: RunOnLocalContext
+//
+// Description: executes a CLI command on the local machine - context-aware variant of RunOnLocal.
+//
+// Notes:
+//
+// - when the ctx is done (cancel, deadline), the process group of the command is killed.
+// - the error is a *TimeoutError when the deadline is exceeded.
+// - the output is the raw combined output (not trimmed) - ExecCliLocal returns the *CommandResult.
+func RunOnLocalContext(ctx context.Context, cli string) (string, error) {
+	var output bytes.Buffer // stdout and stderr - like CombinedOutput
+	_, err := runLocal(ctx, cli, &output, &output, false)
 	if err != nil {
-		return string(output), fmt.Errorf("failed to run command locally: %v, output: %s", err, string(output))
+		return output.String(), fmt.Errorf("failed to run command locally: %w, output: %s", err, output.String())
 	}
-	return string(output), nil
+	return output.String(), nil
 }
 
 // Name: CustomErrorHandler
@@ -198,34 +386,11 @@ func NoOpErrorHandler(err error, logger logx.Logger) bool {
 // - isLocal: bool: Whether to run the command locally or remotely.
 // - remoteHost: string: The hostname or IP address of the remote host if running remotely.
 // Notes:
-//   - It uses isLocal to decide the execution method and remoteHost for SSH connection.
+//   - It uses isLocal and remoteHost to get the executor (see GetExecutor) - use ExecuteCliQueryWith to provide the executor.
 func ExecuteCliQuery(cli string, logger logx.Logger, isLocal bool, remoteHost string, errorHandler CustomErrorHandler) (string, error) {
-	var output string
-	var err error
-
-	// 1. Determine execution environment and run the command
-	if isLocal {
-		logger.Debugf("running on local: %s", cli)
-		output, err = RunOnLocal(cli)
-	} else {
-		// Ensure remoteHost is not empty if running remotely (good practice)
-		if remoteHost == "" {
-			return "", errors.New("remote host cannot be empty when running remotely")
-		}
-		logger.Debugf("running on remote: %s : %s", remoteHost, cli)
-		output, err = RunCliSsh(remoteHost, cli)
-	}
-
-	// 2. Handle "errors" that are not true errors
-	if errorHandler(err, logger) {
-		return output, nil
+	// Ensure remoteHost is not empty if running remotely (good practice)
+	if !isLocal && remoteHost == "" {
+		return "", errors.New("remote host cannot be empty when running remotely")
 	}
-
-	// 3. Handle true execution errors
-	if err != nil {
-		// Return a wrapped error that includes the command run
-		return output, fmt.Errorf("failed to run command: %s: %w", cli, err)
-	}
-
-	return output, nil
+	return ExecuteCliQueryWith(context.Background(), GetExecutor(isLocal, remoteHost), cli, logger, errorHandler)
 }
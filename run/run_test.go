@@ -0,0 +1,73 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestRunOnLocalContext
+func TestRunOnLocalContext(t *testing.T) {
+	// create inputs for the test : a command that forks a process that creates a marker file
+	marker := filepath.Join(t.TempDir(), "marker")
+	cli := "(sleep 1; touch " + marker + ") & wait"
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Define test cases.
+	tests := []struct {
+		name        string        // test case name
+		cli         string        // the input
+		timeout     time.Duration // the input (0 for no timeout)
+		ctx         context.Context
+		want        string // expected output (raw combined output)
+		wantTimeout bool   // expected TimeoutError
+		wantErr     bool   // expected error
+	}{
+		{name: "Case 1: command completes", cli: "echo ok", ctx: context.Background(), want: "ok\n"},
+		{name: "Case 2: non-zero exit code is not a timeout", cli: "echo ko >&2; exit 2", timeout: time.Second, ctx: context.Background(), want: "ko\n", wantErr: true},
+		{name: "Case 3: deadline exceeded", cli: cli, timeout: 200 * time.Millisecond, ctx: context.Background(), wantTimeout: true, wantErr: true},
+		{name: "Case 4: cancelled is not a timeout", cli: "sleep 10", ctx: cancelledCtx, wantErr: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			start := time.Now()
+			got, err := RunOnLocalContext(ctx, tt.cli)
+			assert.Less(t, time.Since(start), 900*time.Millisecond)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, got)
+			}
+
+			var timeoutErr *TimeoutError
+			assert.Equal(t, tt.wantTimeout, errors.As(err, &timeoutErr))
+			assert.Equal(t, tt.wantTimeout, errors.Is(err, context.DeadlineExceeded))
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.want != "" {
+					assert.Equal(t, 1, strings.Count(err.Error(), tt.want)) // the output is given once
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// the process group is killed: the forked process never creates the marker
+	time.Sleep(1500 * time.Millisecond)
+	_, err := os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -1,6 +1,7 @@
 package run
 
 import (
+	"encoding/base64"
 	"fmt"
 
 	"github.com/abtransitionit/gocore/errorx"
@@ -85,3 +86,30 @@ func IsVmSshReachable(vmName string) (bool, error) {
 
 	return true, nil
 }
+
+// Name: getRemoteCommand
+//
+// Description: returns the command that runs a CLI on a remote host and terminates it when the connection is lost.
+//
+// Inputs:
+//
+// - cli: string: the CLI to run.
+// - shell: string: the remote shell that runs the CLI (eg. "$SHELL -l", "sh").
+//
+// Notes:
+//
+//   - The CLI is Base64 encoded to avoid issues with complex quotes and special characters.
+//   - Without a pty, killing the local ssh client (or closing the SSH session) does not stop the remote process.
+//     A watcher reads the standard input of the session: on EOF (the client is gone) it kills the process group of the command (TERM then KILL).
+//   - the client must keep the standard input of the session open while the command runs.
+//   - the command contains no single quote: it can be single quoted.
+func getRemoteCommand(cli, shell string) string {
+	cliEncoded := base64.StdEncoding.EncodeToString([]byte(cli))
+	return fmt.Sprintf(
+		`echo %s | base64 --decode | %s & pid=$!; exec 3<&0; `+
+			`(while read -r _ <&3; do :; done; trap "" TERM; kill -TERM 0; sleep 2; kill -KILL 0) & watcher=$!; `+
+			`wait $pid; rc=$?; kill $watcher 2>/dev/null; exit $rc`,
+		cliEncoded,
+		shell,
+	)
+}
@@ -0,0 +1,649 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abtransitionit/gocore/errorx"
+	"github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Description: the time to wait for the TCP connection and the SSH handshake (same as -o ConnectTimeout=5)
+const sshConnectTimeout = 5 * time.Second
+
+// Description: the interval of the keepalive requests on a pooled connection (same as -o ServerAliveInterval=30)
+const sshKeepAliveInterval = 30 * time.Second
+
+// Description: the private keys tried when the ssh config defines no IdentityFile (same order as OpenSSH)
+var sshDefaultIdentityList = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// Name: SshOption
+//
+// Description: configures a SshExecutor.
+//
+// Notes:
+//
+// - the zero value reads ~/.ssh/config (then /etc/ssh/ssh_config), the ssh agent and ~/.ssh/known_hosts - like the ssh CLI.
+type SshOption struct {
+	ConfigPath      string              // the ssh config file (default: ~/.ssh/config then /etc/ssh/ssh_config)
+	User            string              // the user when the ssh config defines none (default: the current user)
+	AuthMethods     []ssh.AuthMethod    // replaces the default authentication (the ssh agent then the IdentityFile keys)
+	HostKeyCallback ssh.HostKeyCallback // replaces the default host key check (the UserKnownHostsFile files, unless StrictHostKeyChecking is no)
+	ConnectTimeout  time.Duration       // default: 5s
+	KeepAlive       time.Duration       // the interval of the keepalive requests on a pooled connection (default: 30s)
+}
+
+// Name: SshExecutor
+//
+// Description: runs CLIs on remote hosts over native SSH connections (golang.org/x/crypto/ssh).
+//
+// Notes:
+//
+// - one authenticated connection per host is kept and reused across commands (a session per command).
+// - a host is an alias resolved with the ssh config (HostName, Port, User, IdentityFile, UserKnownHostsFile, StrictHostKeyChecking, IdentityAgent).
+// - safe for concurrent use: the commands to the same host share the connection.
+// - a pooled connection that does not answer a keepalive request is dropped: the next command dials again.
+// - ProxyJump and ProxyCommand are not supported: the hosts that need them fail with a ConnectionError (use the ssh CLI executor - GetExecutor).
+// - Close the executor to close the connections.
+type SshExecutor struct {
+	opt      SshOption
+	settings *ssh_config.UserSettings
+	mu       sync.Mutex
+	connMap  map[string]*sshConn // host alias > pooled connection
+}
+
+// Description: a pooled connection to a host
+//
+// Notes:
+// - mu is held while dialing: the concurrent commands to a host wait for a single handshake.
+type sshConn struct {
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// Description: the connection settings of a host resolved from the ssh config
+type sshHostConfig struct {
+	alias          string
+	addr           string // host:port
+	user           string
+	identityList   []string
+	knownHostsList []string
+	strictHostKey  bool
+	agentSocket    string
+}
+
+// Name: NewSshExecutor
+//
+// Description: constructor that returns an instance of SshExecutor.
+//
+// Example Usage:
+//
+//	executor := run.NewSshExecutor(run.SshOption{})
+//	defer executor.Close()
+//	output, err := executor.Run(ctx, "o1u", "uname -a")
+func NewSshExecutor(opt SshOption) *SshExecutor {
+	if opt.ConnectTimeout <= 0 {
+		opt.ConnectTimeout = sshConnectTimeout
+	}
+	if opt.KeepAlive <= 0 {
+		opt.KeepAlive = sshKeepAliveInterval
+	}
+	settings := &ssh_config.UserSettings{IgnoreErrors: true}
+	if opt.ConfigPath != "" {
+		configPath := opt.ConfigPath
+		settings.ConfigFinder(func() string { return configPath })
+	}
+	return &SshExecutor{
+		opt:      opt,
+		settings: settings,
+		connMap:  make(map[string]*sshConn),
+	}
+}
+
+// Name: Run
+//
+// Description: Executes a command on a remote host over the pooled SSH connection - native equivalent of RunCliSshContext.
+//
+// Inputs:
+//
+// - ctx: context.Context: when the ctx is done (cancel, deadline), the remote command is killed and the session closed.
+// - host: string: The alias of the host (e.g., "o1u").
+// - cli: string: The command string to be executed on the remote host.
+//
+// Return:
+//
+// - string: The combined standard output and standard error of the remote command (trimmed).
+// - error: An error if the host cannot be reached or if the command fails (see Exec).
+//
+// Notes:
+//
+// - as RunCliSsh, the command is Base64 encoded and run by the remote login shell.
+// - a broken pooled connection is dropped and dialed again once.
+func (executor *SshExecutor) Run(ctx context.Context, host, cli string) (string, error) {
+	result, err := executor.Exec(ctx, host, cli)
+	return result.getOutput(), err
+}
+
+// Name: Exec
+//
+// Description: Executes a command on a remote host over the pooled SSH connection and returns its structured result.
+//
+// Return:
+//
+// - *CommandResult: exit code, stdout, stderr, duration, signal - also set when the command fails.
+// - error: a *ConnectionError, a *CommandNotFoundError (exit code 127), a *ExitError (non-zero exit code), a *TimeoutError (deadline exceeded) or the ctx error (cancelled).
+func (executor *SshExecutor) Exec(ctx context.Context, host, cli string) (*CommandResult, error) {
+	var capture outputCapture
+	stdout, stderr := capture.getWriters()
+	result, err := executor.runSession(ctx, host, cli, stdout, stderr)
+	capture.setResult(result)
+	if err != nil {
+		return result, errorx.Wrap(err, "failed to run remote command on '%s': %s", host, result.Output)
+	}
+	return result, nil
+}
+
+// Name: RunStream
+//
+// Description: Executes a command on a remote host over the pooled SSH connection and streams its output.
+//
+// Inputs:
+//
+// - stdout, stderr: io.Writer: receive the output of the remote command as it is produced (can be the same writer).
+//
+// Notes:
+//
+// - same as Exec except the output is written to the writers instead of being captured in the result.
+func (executor *SshExecutor) RunStream(ctx context.Context, host, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+	var mu sync.Mutex // stdout and stderr are copied by 2 goroutines
+	result, err := executor.runSession(ctx, host, cli, &lockedWriter{mu: &mu, w: stdout}, &lockedWriter{mu: &mu, w: stderr})
+	if err != nil {
+		return result, errorx.Wrap(err, "failed to run remote command on '%s'", host)
+	}
+	return result, nil
+}
+
+// Name: ForHost
+//
+// Description: returns an Executor that runs the commands on a host over the pooled connection.
+//
+// Example Usage:
+//
+//	output, err := run.NewSshExecutor(run.SshOption{}).ForHost("o1u").Run(ctx, "uname -a")
+func (executor *SshExecutor) ForHost(host string) Executor {
+	return &sshHostExecutor{pool: executor, host: host}
+}
+
+// Description: runs a command in a new session on the pooled connection to a host
+//
+// Return:
+// - the result: the output is not set (it is written to the writers)
+// - a typed error (see Exec)
+//
+// Notes:
+// - the command is killed and the session closed when the ctx is done - closing the session terminates the remote process group (see getRemoteCommand)
+// - the writers must be safe for concurrent use
+func (executor *SshExecutor) runSession(ctx context.Context, host, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+	result := &CommandResult{Command: cli, Host: host, ExitCode: -1, StartTime: time.Now()}
+
+	// step: get a session on the pooled connection
+	session, err := executor.newSession(ctx, host)
+	if err != nil {
+		result.Duration = time.Since(result.StartTime)
+		if ctx.Err() != nil {
+			return result, getCtxError(ctx, result)
+		}
+		return result, err
+	}
+	defer session.Close()
+
+	// step: define the remote command - terminated when the session is closed
+	command := getRemoteCommand(cli, "$SHELL -l")
+
+	// step: Run the command - kill it when the ctx is done
+	session.Stdout = stdout
+	session.Stderr = stderr
+	stdin, err := session.StdinPipe() // kept open while the command runs - see getRemoteCommand
+	if err != nil {
+		result.Duration = time.Since(result.StartTime)
+		return result, &ConnectionError{Host: host, Err: err}
+	}
+	defer stdin.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGKILL)
+			_ = session.Close()
+		case <-done:
+		}
+	}()
+	err = session.Run(command)
+	result.Duration = time.Since(result.StartTime)
+
+	// manage error
+	var exitErr *ssh.ExitError
+	switch {
+	case ctx.Err() != nil:
+		// handle specific error explicitly: the command was killed
+		return result, getCtxError(ctx, result)
+	case err == nil:
+		result.ExitCode = 0
+		return result, nil
+	case errors.As(err, &exitErr):
+		// handle specific error explicitly: the command exited with a non-zero code or was killed by a signal
+		result.ExitCode = exitErr.ExitStatus()
+		result.Signal = exitErr.Signal()
+		if result.Signal != "" {
+			result.ExitCode = -1
+		}
+		return result, getExitError(result)
+	default:
+		// handle generic error explicitly: eg. the connection is lost (no exit status)
+		return result, &ConnectionError{Host: host, Err: err}
+	}
+}
+
+// Name: IsReachable
+//
+// Description: Checks if a host is reachable via SSH - native equivalent of IsVmSshReachable.
+//
+// Return:
+//
+// - bool: `true` if an authenticated connection to the host exists or can be established.
+// - error: The reason why the host is not reachable.
+//
+// Notes:
+//
+// - the connection is kept in the pool: the next command to the host does not pay the handshake.
+func (executor *SshExecutor) IsReachable(ctx context.Context, host string) (bool, error) {
+	if _, err := executor.getClient(ctx, host); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Name: Close
+//
+// Description: Closes the pooled connections.
+func (executor *SshExecutor) Close() error {
+	executor.mu.Lock()
+	connMap := executor.connMap
+	executor.connMap = make(map[string]*sshConn)
+	executor.mu.Unlock()
+
+	var errList []error
+	for host, conn := range connMap {
+		conn.mu.Lock()
+		if conn.client != nil {
+			if err := conn.client.Close(); err != nil {
+				errList = append(errList, fmt.Errorf("closing connection to '%s': %w", host, err))
+			}
+			conn.client = nil
+		}
+		conn.mu.Unlock()
+	}
+	if len(errList) > 0 {
+		return errorx.Wrap(errList[0], "failed to close %d ssh connection(s)", len(errList))
+	}
+	return nil
+}
+
+// Description: returns a new session on the pooled connection to a host
+//
+// Notes:
+// - a connection that cannot open a session is considered broken: it is dropped and dialed again once
+// - the ctx error is returned when the ctx is done before the session is opened
+func (executor *SshExecutor) newSession(ctx context.Context, host string) (*ssh.Session, error) {
+	client, err := executor.getClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	session, err := newSessionContext(ctx, client)
+	if err == nil || ctx.Err() != nil {
+		return session, err
+	}
+
+	// step: retry once on a new connection
+	executor.dropClient(host, client)
+	client, err = executor.getClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	session, err = newSessionContext(ctx, client)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		executor.dropClient(host, client)
+		return nil, errorx.Wrap(&ConnectionError{Host: host, Err: err}, "failed to open ssh session on '%s'", host)
+	}
+	return session, nil
+}
+
+// Description: opens a session on a connection - bounded by the ctx
+//
+// Notes:
+// - client.NewSession cannot be cancelled: when the ctx is done first, it is left to a goroutine that closes the session it eventually opens
+func newSessionContext(ctx context.Context, client *ssh.Client) (*ssh.Session, error) {
+	type sessionResult struct {
+		session *ssh.Session
+		err     error
+	}
+	resultCh := make(chan sessionResult, 1)
+	go func() {
+		session, err := client.NewSession()
+		resultCh <- sessionResult{session: session, err: err}
+	}()
+	select {
+	case result := <-resultCh:
+		return result.session, result.err
+	case <-ctx.Done():
+		go func() {
+			if result := <-resultCh; result.session != nil {
+				_ = result.session.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Description: returns the pooled connection to a host (dialed on first use)
+func (executor *SshExecutor) getClient(ctx context.Context, host string) (*ssh.Client, error) {
+	executor.mu.Lock()
+	conn, ok := executor.connMap[host]
+	if !ok {
+		conn = &sshConn{}
+		executor.connMap[host] = conn
+	}
+	executor.mu.Unlock()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.client != nil {
+		return conn.client, nil
+	}
+	client, err := executor.dial(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	conn.client = client
+	go executor.keepAlive(host, client)
+	return client, nil
+}
+
+// Description: sends keepalive requests on a pooled connection until it is closed
+//
+// Notes:
+// - a connection that does not answer within the connect timeout is dropped (same as ServerAliveCountMax=1): the commands running on it fail with a ConnectionError
+func (executor *SshExecutor) keepAlive(host string, client *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+	ticker := time.NewTicker(executor.opt.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if err := sendKeepAlive(client, executor.opt.ConnectTimeout); err != nil {
+				executor.dropClient(host, client)
+				return
+			}
+		}
+	}
+}
+
+// Description: sends a keepalive request on a connection and waits for the reply
+//
+// Notes:
+// - the reply is usually a failure (the server does not know the request): any reply means the connection is alive
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		return errors.New("no reply to the keepalive request")
+	}
+}
+
+// Description: closes and removes a broken connection from the pool
+//
+// Notes:
+// - a no-op if the connection was already replaced by another goroutine
+func (executor *SshExecutor) dropClient(host string, client *ssh.Client) {
+	executor.mu.Lock()
+	conn, ok := executor.connMap[host]
+	executor.mu.Unlock()
+	if !ok {
+		return
+	}
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.client == client {
+		_ = client.Close()
+		conn.client = nil
+	}
+}
+
+// Description: opens an authenticated connection to a host
+func (executor *SshExecutor) dial(ctx context.Context, host string) (*ssh.Client, error) {
+
+	// step: resolve the host with the ssh config
+	hostConfig, err := executor.getHostConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	// step: define the client config
+	authList, closeAgent, err := executor.getAuthList(hostConfig)
+	if err != nil {
+		return nil, &ConnectionError{Host: host, Err: err}
+	}
+	defer closeAgent() // the agent signs during the handshake only
+	hostKeyCallback, err := executor.getHostKeyCallback(hostConfig)
+	if err != nil {
+		return nil, &ConnectionError{Host: host, Err: err}
+	}
+	clientConfig := &ssh.ClientConfig{
+		User:            hostConfig.user,
+		Auth:            authList,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         executor.opt.ConnectTimeout,
+	}
+
+	// step: connect - the handshake is bounded by the connect timeout and the ctx
+	dialer := net.Dialer{Timeout: executor.opt.ConnectTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", hostConfig.addr)
+	if err != nil {
+		return nil, errorx.Wrap(&ConnectionError{Host: host, Err: err}, "vm '%s' is not SSH reachable (%s)", host, hostConfig.addr)
+	}
+	deadline := time.Now().Add(executor.opt.ConnectTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = netConn.SetDeadline(deadline)
+	sshConn, chanCh, reqCh, err := ssh.NewClientConn(netConn, hostConfig.addr, clientConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, errorx.Wrap(&ConnectionError{Host: host, Err: err}, "ssh handshake with '%s' (%s) failed", host, hostConfig.addr)
+	}
+	_ = netConn.SetDeadline(time.Time{})
+	return ssh.NewClient(sshConn, chanCh, reqCh), nil
+}
+
+// Description: resolves the connection settings of a host with the ssh config
+func (executor *SshExecutor) getHostConfig(host string) (sshHostConfig, error) {
+	if host == "" {
+		return sshHostConfig{}, errorx.New("host cannot be empty")
+	}
+	hostConfig := sshHostConfig{alias: host}
+
+	// proxies: not supported - fail instead of dialing the host directly
+	for _, key := range []string{"ProxyJump", "ProxyCommand"} {
+		if value := executor.settings.Get(host, key); value != "" && value != "none" {
+			return sshHostConfig{}, &ConnectionError{Host: host, Err: fmt.Errorf("%s %q is set in the ssh config: not supported by the native ssh executor (use the ssh CLI executor)", key, value)}
+		}
+	}
+
+	// host and port
+	hostName := executor.settings.Get(host, "HostName")
+	if hostName == "" {
+		hostName = host
+	}
+	hostName = strings.ReplaceAll(hostName, "%h", host)
+	port := executor.settings.Get(host, "Port")
+	if port == "" {
+		port = "22"
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return sshHostConfig{}, errorx.Wrap(err, "invalid ssh port %q for '%s'", port, host)
+	}
+	hostConfig.addr = net.JoinHostPort(hostName, port)
+
+	// user
+	hostConfig.user = executor.settings.Get(host, "User")
+	if hostConfig.user == "" {
+		hostConfig.user = executor.opt.User
+	}
+	if hostConfig.user == "" {
+		currentUser, err := user.Current()
+		if err != nil {
+			return sshHostConfig{}, errorx.Wrap(err, "failed to get the current user")
+		}
+		hostConfig.user = currentUser.Username
+	}
+
+	// keys
+	identityList := executor.settings.GetAll(host, "IdentityFile")
+	if len(identityList) == 0 || (len(identityList) == 1 && identityList[0] == ssh_config.Default("IdentityFile")) {
+		identityList = sshDefaultIdentityList
+	}
+	for _, identity := range identityList {
+		hostConfig.identityList = append(hostConfig.identityList, expandHome(identity))
+	}
+
+	// known hosts
+	knownHosts := executor.settings.Get(host, "UserKnownHostsFile")
+	if knownHosts == "" {
+		knownHosts = "~/.ssh/known_hosts"
+	}
+	for _, knownHostsFile := range strings.Fields(knownHosts) {
+		hostConfig.knownHostsList = append(hostConfig.knownHostsList, expandHome(knownHostsFile))
+	}
+	hostConfig.strictHostKey = executor.settings.Get(host, "StrictHostKeyChecking") != "no"
+
+	// agent
+	hostConfig.agentSocket = os.Getenv("SSH_AUTH_SOCK")
+	if identityAgent := executor.settings.Get(host, "IdentityAgent"); identityAgent != "" {
+		switch identityAgent {
+		case "none":
+			hostConfig.agentSocket = ""
+		case "SSH_AUTH_SOCK":
+		default:
+			hostConfig.agentSocket = expandHome(os.ExpandEnv(identityAgent))
+		}
+	}
+	return hostConfig, nil
+}
+
+// Description: returns the authentication methods of a host
+//
+// Notes:
+// - the ssh agent is tried first then the IdentityFile keys that can be read without a passphrase
+// - the returned function closes the connection to the agent
+func (executor *SshExecutor) getAuthList(hostConfig sshHostConfig) ([]ssh.AuthMethod, func(), error) {
+	closeAgent := func() {}
+	if len(executor.opt.AuthMethods) > 0 {
+		return executor.opt.AuthMethods, closeAgent, nil
+	}
+	var authList []ssh.AuthMethod
+
+	// agent
+	if hostConfig.agentSocket != "" {
+		if agentConn, err := net.Dial("unix", hostConfig.agentSocket); err == nil {
+			closeAgent = func() { agentConn.Close() }
+			authList = append(authList, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+		}
+	}
+
+	// keys
+	var signerList []ssh.Signer
+	for _, identity := range hostConfig.identityList {
+		pemBytes, err := os.ReadFile(identity)
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(pemBytes)
+		if err != nil {
+			continue // eg. a key protected by a passphrase: expected to be in the agent
+		}
+		signerList = append(signerList, signer)
+	}
+	if len(signerList) > 0 {
+		authList = append(authList, ssh.PublicKeys(signerList...))
+	}
+
+	if len(authList) == 0 {
+		return nil, closeAgent, errorx.New("no ssh agent nor usable private key for '%s'", hostConfig.alias)
+	}
+	return authList, closeAgent, nil
+}
+
+// Description: returns the host key check of a host
+func (executor *SshExecutor) getHostKeyCallback(hostConfig sshHostConfig) (ssh.HostKeyCallback, error) {
+	if executor.opt.HostKeyCallback != nil {
+		return executor.opt.HostKeyCallback, nil
+	}
+	if !hostConfig.strictHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	var fileList []string
+	for _, knownHostsFile := range hostConfig.knownHostsList {
+		if _, err := os.Stat(knownHostsFile); err == nil {
+			fileList = append(fileList, knownHostsFile)
+		}
+	}
+	if len(fileList) == 0 {
+		return nil, errorx.New("no known_hosts file to check the host key of '%s': %v", hostConfig.alias, hostConfig.knownHostsList)
+	}
+	hostKeyCallback, err := knownhosts.New(fileList...)
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to read known_hosts files %v", fileList)
+	}
+	return hostKeyCallback, nil
+}
+
+// Description: replaces a leading ~ by the home folder of the current user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
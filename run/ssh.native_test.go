@@ -0,0 +1,301 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Description: an in-process SSH server that runs the exec requests with the local shell
+type testSshServer struct {
+	listener net.Listener
+	hostKey  ssh.Signer
+	nbConn   atomic.Int32 // the number of handshakes
+	mute     atomic.Bool  // when true, the global requests (keepalive) get no reply
+}
+
+// Description: starts a SSH server that accepts the public key of a client
+func newTestSshServer(t *testing.T, clientKey ssh.PublicKey) *testSshServer {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	assert.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown public key for %q", conn.User())
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &testSshServer{listener: listener, hostKey: hostKey}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(netConn, config)
+		}
+	}()
+	return server
+}
+
+// Description: serves the sessions of a connection
+func (server *testSshServer) serve(netConn net.Conn, config *ssh.ServerConfig) {
+	_, chanCh, reqCh, err := ssh.NewServerConn(netConn, config)
+	if err != nil {
+		return
+	}
+	server.nbConn.Add(1)
+	go func() {
+		for req := range reqCh {
+			if req.WantReply && !server.mute.Load() {
+				req.Reply(false, nil)
+			}
+		}
+	}()
+	for newChannel := range chanCh {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requestCh, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go serveSession(channel, requestCh)
+	}
+}
+
+// Description: runs the exec request of a session
+//
+// Notes:
+// - the signal requests are ignored (like most sshd): a cancelled command is terminated by the remote command itself when the session is closed (see getRemoteCommand)
+func serveSession(channel ssh.Channel, requestCh <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requestCh {
+		switch req.Type {
+		case "exec":
+			command := string(req.Payload[4:])
+			req.Reply(true, nil)
+			go func() {
+				cmd := exec.Command("sh", "-c", command)
+				cmd.Env = append(os.Environ(), "SHELL=/bin/sh")
+				cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} // like sshd (setsid)
+				stdin, _ := cmd.StdinPipe()                           // not waited by Wait: the client keeps the stdin open until the exit status
+				cmd.Stdout = channel
+				cmd.Stderr = channel.Stderr()
+				err := cmd.Start()
+				go func() {
+					io.Copy(stdin, channel)
+					stdin.Close()
+				}()
+				status := uint32(0)
+				if err == nil {
+					err = cmd.Wait()
+				}
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					status = uint32(exitErr.ExitCode())
+				} else if err != nil {
+					status = 255
+				}
+				payload := make([]byte, 4)
+				binary.BigEndian.PutUint32(payload, status)
+				channel.SendRequest("exit-status", false, payload)
+				channel.Close()
+			}()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// Description: writes a client key, a known_hosts file and a ssh config that defines the alias "vm1" for the server (and the alias "jumped" behind it)
+func writeTestSshConfig(t *testing.T, server *testSshServer, clientPriv ed25519.PrivateKey) string {
+	dir := t.TempDir()
+
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	assert.NoError(t, err)
+	keyPath := filepath.Join(dir, "id_ed25519")
+	assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600))
+
+	addr := server.listener.Addr().String()
+	knownHostsPath := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, server.hostKey.PublicKey())
+	assert.NoError(t, os.WriteFile(knownHostsPath, []byte(line+"\n"), 0o600))
+
+	host, port, _ := net.SplitHostPort(addr)
+	config := fmt.Sprintf("Host vm1\n  HostName %s\n  Port %s\n  User tester\n  IdentityFile %s\n  UserKnownHostsFile %s\nHost jumped\n  ProxyJump vm1\n", host, port, keyPath, knownHostsPath)
+	configPath := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(configPath, []byte(config), 0o600))
+	return configPath
+}
+
+// Name: TestSshExecutor
+func TestSshExecutor(t *testing.T) {
+	// create inputs for the test : a SSH server, a ssh config and an executor
+	t.Setenv("SSH_AUTH_SOCK", "")
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(clientPub)
+	assert.NoError(t, err)
+	server := newTestSshServer(t, sshPub)
+	executor := NewSshExecutor(SshOption{ConfigPath: writeTestSshConfig(t, server, clientPriv)})
+	defer executor.Close()
+
+	// Define test cases.
+	tests := []struct {
+		name    string // test case name
+		host    string // the input
+		cli     string // the input
+		want    string // expected output
+		wantErr bool   // expected error
+	}{
+		{name: "Case 1: simple command", host: "vm1", cli: "echo hello", want: "hello"},
+		{name: "Case 2: quotes and pipes", host: "vm1", cli: `echo "a 'b'" | tr a z`, want: "z 'b'"},
+		{name: "Case 3: stderr is captured", host: "vm1", cli: "echo oops >&2", want: "oops"},
+		{name: "Case 4: non-zero exit status", host: "vm1", cli: "echo ko; exit 3", want: "ko", wantErr: true},
+		{name: "Case 5: empty host", host: "", cli: "true", wantErr: true},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executor.Run(context.Background(), tt.host, tt.cli)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// the commands to the same host share one connection - including concurrent ones
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := executor.Run(context.Background(), "vm1", "true")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), server.nbConn.Load())
+
+	// the host executor streams stdout and stderr
+	var stdout, stderr bytes.Buffer
+	hostExecutor := executor.ForHost("vm1")
+	assert.Equal(t, "vm1", hostExecutor.Target())
+	result, err := hostExecutor.RunStream(context.Background(), "echo out; echo err >&2", &stdout, &stderr)
+	assert.NoError(t, err)
+	assert.True(t, result.Success())
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+
+	// a cancelled command is killed
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	marker := filepath.Join(t.TempDir(), "marker")
+	_, err = executor.Run(ctx, "vm1", "sleep 1; touch "+marker)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var timeoutErr *TimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+	time.Sleep(1500 * time.Millisecond) // the remote command is terminated: it never creates the marker
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+
+	// a done ctx fails before the session is opened
+	cancelledCtx, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	_, err = executor.Run(cancelledCtx, "vm1", "true")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// a closed connection is dialed again
+	assert.NoError(t, executor.Close())
+	got, err := executor.Run(context.Background(), "vm1", "echo again")
+	assert.NoError(t, err)
+	assert.Equal(t, "again", got)
+	assert.Equal(t, int32(2), server.nbConn.Load())
+
+	// the errors are typed and carry the result
+	result, err = executor.Exec(context.Background(), "vm1", "echo out; echo err >&2; exit 3")
+	var exitErr *ExitError
+	assert.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	_, err = executor.Exec(context.Background(), "vm1", "nosuchcommand-xyz")
+	var notFoundErr *CommandNotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+	_, err = executor.Exec(context.Background(), "unknown-host.invalid", "true")
+	var connErr *ConnectionError
+	assert.ErrorAs(t, err, &connErr)
+
+	// a host behind a proxy fails instead of being dialed directly
+	_, err = executor.Exec(context.Background(), "jumped", "true")
+	assert.ErrorAs(t, err, &connErr)
+	assert.ErrorContains(t, err, "ProxyJump")
+}
+
+// Name: TestSshExecutorKeepAlive
+func TestSshExecutorKeepAlive(t *testing.T) {
+	// create inputs for the test : a SSH server and an executor with a short keepalive interval
+	t.Setenv("SSH_AUTH_SOCK", "")
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(clientPub)
+	assert.NoError(t, err)
+	server := newTestSshServer(t, sshPub)
+	executor := NewSshExecutor(SshOption{
+		ConfigPath:     writeTestSshConfig(t, server, clientPriv),
+		ConnectTimeout: 200 * time.Millisecond,
+		KeepAlive:      50 * time.Millisecond,
+	})
+	defer executor.Close()
+
+	// a connection that answers the keepalive requests is kept
+	_, err = executor.Run(context.Background(), "vm1", "true")
+	assert.NoError(t, err)
+	time.Sleep(300 * time.Millisecond)
+	_, err = executor.Run(context.Background(), "vm1", "true")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), server.nbConn.Load())
+
+	// a connection that does not answer is dropped: the next command dials again
+	server.mute.Store(true)
+	time.Sleep(500 * time.Millisecond)
+	server.mute.Store(false)
+	_, err = executor.Run(context.Background(), "vm1", "true")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), server.nbConn.Load())
+}
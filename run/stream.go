@@ -0,0 +1,223 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/abtransitionit/gocore/color"
+)
+
+// Name: OutputLine
+//
+// Description: a line of output of a command - delivered by ExecStream.
+type OutputLine struct {
+	Host   string // the target of the executor: "local" or the remote host
+	Stream string // "stdout" or "stderr"
+	Text   string // the line without the trailing newline
+}
+
+// Name: ExecStream
+//
+// Description: runs a CLI with an executor and delivers its output line by line to a callback.
+//
+// Inputs:
+//
+// - executor: Executor: where the command runs (local, remote, fake).
+// - onLine: func(OutputLine): called for each line of stdout and stderr, as it is produced.
+//
+// Return:
+//
+// - *CommandResult: the full output is also captured (Stdout, Stderr, Output).
+// - error: the typed error of the executor (see Executor).
+//
+// Notes:
+//
+// - the calls to onLine are serialized: it needs no lock.
+// - a last line without trailing newline is delivered when the command exits.
+//
+// Example Usage:
+//
+//	result, err := run.ExecStream(ctx, executor, "apt-get update", func(line run.OutputLine) {
+//		logger.Infof("%s: %s", line.Host, line.Text)
+//	})
+func ExecStream(ctx context.Context, executor Executor, cli string, onLine func(line OutputLine)) (*CommandResult, error) {
+	var mu sync.Mutex // the 2 line writers share the lock: one call to onLine at a time
+	host := executor.Target()
+	stdout := &lockedWriter{mu: &mu, w: NewLineWriter(func(text string) { onLine(OutputLine{Host: host, Stream: "stdout", Text: text}) })}
+	stderr := &lockedWriter{mu: &mu, w: NewLineWriter(func(text string) { onLine(OutputLine{Host: host, Stream: "stderr", Text: text}) })}
+	return ExecStreamTo(ctx, executor, cli, stdout, stderr)
+}
+
+// Name: ExecStreamTo
+//
+// Description: runs a CLI with an executor, writes its output to the writers as it is produced and captures it in the result.
+//
+// Inputs:
+//
+// - stdout, stderr: io.Writer: receive the output of the command (can be the same writer - eg. NewHostWriter).
+//
+// Return:
+//
+// - *CommandResult: the full output is also captured (Stdout, Stderr, Output).
+// - error: the typed error of the executor (see Executor).
+//
+// Notes:
+//
+// - the writers that buffer a partial line (LineWriter - eg. NewHostWriter) are flushed when the command exits.
+//
+// Example Usage:
+//
+//	writer := run.NewHostWriter(os.Stdout, "o1u")
+//	result, err := run.ExecStreamTo(ctx, run.GetExecutor(false, "o1u"), "apt-get update", writer, writer)
+func ExecStreamTo(ctx context.Context, executor Executor, cli string, stdout, stderr io.Writer) (*CommandResult, error) {
+
+	// step: tee the output to the writers and to the capture
+	var capture outputCapture
+	captureStdout, captureStderr := capture.getWriters()
+	result, err := executor.RunStream(ctx, cli, io.MultiWriter(captureStdout, stdout), io.MultiWriter(captureStderr, stderr))
+
+	// step: deliver the last partial lines (flushing the same writer twice is a no-op)
+	flushWriter(stdout)
+	flushWriter(stderr)
+
+	// step: set the output of the result
+	if result == nil {
+		result = &CommandResult{Command: cli, Host: executor.Target(), ExitCode: -1}
+	}
+	capture.setResult(result)
+	return result, err
+}
+
+// Description: flushes a writer that buffers a partial line
+func flushWriter(w io.Writer) {
+	if lw, ok := w.(*lockedWriter); ok {
+		lw.mu.Lock()
+		defer lw.mu.Unlock()
+		w = lw.w
+	}
+	if flusher, ok := w.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+}
+
+// Name: LineWriter
+//
+// Description: a writer that calls a function for each line written to it.
+//
+// Notes:
+//
+// - the line is given without its trailing newline ("\n" or "\r\n").
+// - a partial line is buffered until its newline is written or Flush is called.
+// - safe for concurrent use.
+type LineWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	onLine func(line string)
+}
+
+// Name: NewLineWriter
+//
+// Description: constructor that returns an instance of LineWriter.
+func NewLineWriter(onLine func(line string)) *LineWriter {
+	return &LineWriter{onLine: onLine}
+}
+
+// Description: buffers p and calls the function for each complete line
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(lw.buf[:i]), "\r")
+		lw.buf = lw.buf[i+1:]
+		lw.onLine(line)
+	}
+	return len(p), nil
+}
+
+// Name: Flush
+//
+// Description: calls the function for the partial line buffered (if any).
+func (lw *LineWriter) Flush() {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if len(lw.buf) == 0 {
+		return
+	}
+	line := strings.TrimSuffix(string(lw.buf), "\r")
+	lw.buf = nil
+	lw.onLine(line)
+}
+
+// Description: the colors of the host prefixes (red is left to the errors)
+var hostColorList = []string{color.Cyan, color.Green, color.Yellow, color.Magenta, color.Blue}
+
+// Description: the color assigned to each host - in the order of first use
+var (
+	hostColorMu  sync.Mutex
+	hostColorMap = map[string]string{}
+)
+
+// Description: serializes the lines written by the HostWriters (they may share the same writer)
+var hostWriterMu sync.Mutex
+
+// Name: GetHostColor
+//
+// Description: returns the ANSI color of a host.
+//
+// Notes:
+//
+// - the hosts get the colors in turn, in the order of first use: the same host always gets the same color.
+func GetHostColor(host string) string {
+	hostColorMu.Lock()
+	defer hostColorMu.Unlock()
+	if code, ok := hostColorMap[host]; ok {
+		return code
+	}
+	code := hostColorList[len(hostColorMap)%len(hostColorList)]
+	hostColorMap[host] = code
+	return code
+}
+
+// Name: NewHostWriter
+//
+// Description: returns a LineWriter that writes each line to w prefixed with the host name (in the color of the host).
+//
+// Notes:
+//
+// - each line is written with a single Write: the lines of concurrent hosts sharing w never interleave.
+// - use NewPrefixWriter for a custom prefix or no color.
+//
+// Example Usage:
+//
+//	writer := run.NewHostWriter(os.Stdout, "o1u") // [o1u] Hit:1 http://archive.ubuntu.com/ubuntu noble InRelease
+func NewHostWriter(w io.Writer, host string) *LineWriter {
+	return NewPrefixWriter(w, "["+host+"]", GetHostColor(host))
+}
+
+// Name: NewPrefixWriter
+//
+// Description: returns a LineWriter that writes each line to w prefixed with prefix.
+//
+// Inputs:
+//
+// - prefix: string: the tag of the lines (eg. "[o1u]").
+// - colorCode: string: the ANSI color of the prefix (eg. color.Cyan) - "" for no color.
+func NewPrefixWriter(w io.Writer, prefix, colorCode string) *LineWriter {
+	if colorCode != "" {
+		prefix = color.Colorize(prefix, colorCode)
+	}
+	return NewLineWriter(func(line string) {
+		hostWriterMu.Lock()
+		defer hostWriterMu.Unlock()
+		fmt.Fprintf(w, "%s %s\n", prefix, line)
+	})
+}
@@ -0,0 +1,154 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/abtransitionit/gocore/color"
+	"github.com/stretchr/testify/assert"
+)
+
+// Name: TestLineWriter
+func TestLineWriter(t *testing.T) {
+	// Define test cases.
+	tests := []struct {
+		name      string   // test case name
+		chunkList []string // the input: the successive writes
+		want      []string // expected lines
+	}{
+		{name: "Case 1: one line per write", chunkList: []string{"a\n", "b\n"}, want: []string{"a", "b"}},
+		{name: "Case 2: lines split across writes", chunkList: []string{"he", "llo\nwor", "ld\n"}, want: []string{"hello", "world"}},
+		{name: "Case 3: several lines in one write", chunkList: []string{"a\nb\n\nc\n"}, want: []string{"a", "b", "", "c"}},
+		{name: "Case 4: CRLF", chunkList: []string{"a\r\nb\r\n"}, want: []string{"a", "b"}},
+		{name: "Case 5: partial last line is flushed", chunkList: []string{"a\nb"}, want: []string{"a", "b"}},
+	}
+
+	// Run test cases.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			lw := NewLineWriter(func(line string) { got = append(got, line) })
+			for _, chunk := range tt.chunkList {
+				n, err := lw.Write([]byte(chunk))
+				assert.NoError(t, err)
+				assert.Equal(t, len(chunk), n)
+			}
+			lw.Flush()
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Name: TestExecStream
+func TestExecStream(t *testing.T) {
+	// the lines are delivered with their stream and the full output is captured
+	var lineList []OutputLine
+	result, err := ExecStream(context.Background(), LocalExecutor{}, "echo a; echo b >&2; printf c", func(line OutputLine) {
+		lineList = append(lineList, line)
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []OutputLine{
+		{Host: "local", Stream: "stdout", Text: "a"},
+		{Host: "local", Stream: "stderr", Text: "b"},
+		{Host: "local", Stream: "stdout", Text: "c"},
+	}, lineList)
+	assert.Equal(t, "a\nc", result.Stdout)
+	assert.Equal(t, "b\n", result.Stderr)
+	assert.Equal(t, 0, result.ExitCode)
+
+	// the error and the exit code of the executor are returned
+	executor := NewFakeExecutor("o1u").On("apt-get", "E: Unable to locate package", &ExitError{Result: &CommandResult{ExitCode: 100}})
+	result, err = ExecStream(context.Background(), executor, "apt-get install nope", func(line OutputLine) {
+		lineList = append(lineList, line)
+	})
+	var exitErr *ExitError
+	assert.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 100, result.ExitCode)
+	assert.Equal(t, "E: Unable to locate package", result.Output)
+	assert.Equal(t, OutputLine{Host: "o1u", Stream: "stdout", Text: "E: Unable to locate package"}, lineList[len(lineList)-1])
+}
+
+// Name: TestHostWriter
+func TestHostWriter(t *testing.T) {
+	// create inputs for the test : hosts that write concurrently to the same writer
+	var out bytes.Buffer
+	hostList := []string{"h1", "h2", "h3", "h4", "h5", "h6"}
+	nbLine := 50
+
+	// write the lines in small chunks
+	var wg sync.WaitGroup
+	for _, host := range hostList {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			writer := NewHostWriter(&out, host)
+			for i := 0; i < nbLine; i++ {
+				for _, chunk := range []string{"line ", fmt.Sprint(i), " of ", host, "\n"} {
+					writer.Write([]byte(chunk))
+				}
+			}
+		}(host)
+	}
+	wg.Wait()
+
+	// each line is whole and prefixed with the host in its color
+	lineList := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lineList, len(hostList)*nbLine)
+	for _, line := range lineList {
+		var host string
+		for _, h := range hostList {
+			if strings.HasSuffix(line, " of "+h) {
+				host = h
+			}
+		}
+		assert.True(t, strings.HasPrefix(line, color.Colorize("["+host+"]", GetHostColor(host))+" line "), line)
+	}
+
+	// the same host always gets the same color, the next hosts get different ones
+	assert.Equal(t, GetHostColor("h1"), GetHostColor("h1"))
+	assert.NotEqual(t, GetHostColor("next1"), GetHostColor("next2"))
+
+	// no color
+	out.Reset()
+	writer := NewPrefixWriter(&out, "[vm]", "")
+	writer.Write([]byte("a\nb"))
+	writer.Flush()
+	assert.Equal(t, "[vm] a\n[vm] b\n", out.String())
+}
+
+// Description: a writer whose dynamic type is not comparable
+type sliceWriter struct {
+	lineList []string // a slice field makes the type uncomparable
+	out      *bytes.Buffer
+}
+
+func (w sliceWriter) Write(p []byte) (int, error) {
+	return w.out.Write(p)
+}
+
+// Name: TestExecStreamTo
+func TestExecStreamTo(t *testing.T) {
+	// create inputs for the test : the same host writer and 2 uncomparable writers
+	executor := LocalExecutor{}
+	var out bytes.Buffer
+	writer := NewPrefixWriter(&out, "[local]", "")
+
+	// the same writer: the last partial line is delivered once
+	result, err := ExecStreamTo(context.Background(), executor, "printf err >&2", writer, writer)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "[local] err\n", out.String())
+
+	// writers of an uncomparable type do not panic
+	var stdout, stderr bytes.Buffer
+	assert.NotPanics(t, func() {
+		_, err = ExecStreamTo(context.Background(), executor, "echo out; echo err >&2", sliceWriter{out: &stdout}, sliceWriter{out: &stderr})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}